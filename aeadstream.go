@@ -0,0 +1,248 @@
+// Online Authenticated-Encryption and its Nonce-Reuse Misuse-Resistance (STREAM)
+// https://eprint.iacr.org/2015/189
+
+package main
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/AirWSW/go-crypto/aes"
+)
+
+// An AEAD stream starts with a header of a version byte and a random salt,
+// followed by chunks of up to AEADStreamChunkSize bytes of plaintext, each
+// sealed with AES-GCM under a key derived from the stream key and the salt
+// and followed by its 16-byte tag. The nonce of the i-th chunk is
+//
+//	0^56 || [i]32 || flag
+//
+// where flag is 1 for the last chunk and 0 otherwise, so dropping, swapping
+// or reordering chunks, or cutting the stream at a chunk boundary, makes a
+// chunk fail to authenticate. The header is the additional data of every
+// chunk. The last chunk is empty only if the whole plaintext is empty.
+const (
+	AEADStreamChunkSize = 64 * 1024
+
+	aeadStreamVersion  = 1
+	aeadStreamSaltSize = 16
+	aeadStreamHeader   = 1 + aeadStreamSaltSize
+)
+
+type aeadStream struct {
+	aead   cipher.AEAD
+	header [aeadStreamHeader]byte
+	nonce  [gcmNonceSize]byte
+	ctr    uint32
+}
+
+// init derives the chunk key from key and the salt in the header.
+func (s *aeadStream) init(key []byte) (err error) {
+	s.aead, err = newSaltedGCM(key, s.header[:])
+	return err
+}
+
+// newSaltedGCM returns AES-GCM keyed by HMAC-SHA256(key, salt) truncated to
+// the length of key, which must be 16, 24 or 32 bytes.
+func newSaltedGCM(key, salt []byte) (cipher.AEAD, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("invalid key size")
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(salt)
+	block, err := aes.NewCipher(mac.Sum(nil)[:len(key)])
+	if err != nil {
+		return nil, err
+	}
+	return NewGCM(block)
+}
+
+// next returns the nonce of the next chunk and advances the counter.
+func (s *aeadStream) next(last bool) ([]byte, error) {
+	if s.ctr == 1<<32-1 {
+		return nil, fmt.Errorf("too many chunks")
+	}
+	binary.BigEndian.PutUint32(s.nonce[7:11], s.ctr)
+	s.nonce[11] = 0
+	if last {
+		s.nonce[11] = 1
+	}
+	s.ctr++
+	return s.nonce[:], nil
+}
+
+// AEADStreamWriter encrypts and authenticates everything written to it as
+// an AEAD stream. Close must be called to write the last chunk; a stream
+// that was not closed is rejected by AEADStreamReader as truncated.
+type AEADStreamWriter struct {
+	aeadStream
+	w   io.Writer
+	buf []byte
+	err error
+}
+
+// NewAEADStreamWriter returns an AEADStreamWriter that encrypts with the
+// given 16, 24 or 32-byte AES key and writes the stream to w, starting with
+// the header.
+func NewAEADStreamWriter(w io.Writer, key []byte) (*AEADStreamWriter, error) {
+	s := &AEADStreamWriter{w: w}
+	s.header[0] = aeadStreamVersion
+	if _, err := io.ReadFull(rand.Reader, s.header[1:]); err != nil {
+		return nil, err
+	}
+	if err := s.init(key); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(s.header[:]); err != nil {
+		return nil, err
+	}
+	s.buf = make([]byte, 0, AEADStreamChunkSize+gcmTagSize)
+	return s, nil
+}
+
+func (s *AEADStreamWriter) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	n := 0
+	for len(p) > 0 {
+		// A full chunk is held back until more data arrives, as it is the
+		// last chunk if the stream is closed now.
+		if len(s.buf) == AEADStreamChunkSize {
+			if s.err = s.flush(false); s.err != nil {
+				return n, s.err
+			}
+		}
+		m := AEADStreamChunkSize - len(s.buf)
+		if m > len(p) {
+			m = len(p)
+		}
+		s.buf = append(s.buf, p[:m]...)
+		p = p[m:]
+		n += m
+	}
+	return n, nil
+}
+
+// flush seals the buffered plaintext as the next chunk and writes it.
+func (s *AEADStreamWriter) flush(last bool) error {
+	nonce, err := s.next(last)
+	if err != nil {
+		return err
+	}
+	s.buf = s.aead.Seal(s.buf[:0], nonce, s.buf, s.header[:])
+	_, err = s.w.Write(s.buf)
+	s.buf = s.buf[:0]
+	return err
+}
+
+// Close writes the last chunk and closes the underlying Writer if it
+// implements io.Closer.
+func (s *AEADStreamWriter) Close() error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.err = s.flush(true); s.err != nil {
+		return s.err
+	}
+	s.err = fmt.Errorf("write to closed AEAD stream")
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// AEADStreamReader decrypts an AEAD stream. Each chunk is authenticated
+// before any of its plaintext is returned, and Read returns io.EOF only
+// after the last chunk has been authenticated, so everything it returns
+// is a prefix of the plaintext that was written.
+type AEADStreamReader struct {
+	aeadStream
+	r   io.Reader
+	buf []byte
+	out []byte
+	eof bool
+	err error
+}
+
+// NewAEADStreamReader returns an AEADStreamReader that reads the header of
+// the stream from r and decrypts it with the given AES key.
+func NewAEADStreamReader(r io.Reader, key []byte) (*AEADStreamReader, error) {
+	s := &AEADStreamReader{r: r}
+	if _, err := io.ReadFull(r, s.header[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if s.header[0] != aeadStreamVersion {
+		return nil, fmt.Errorf("unsupported AEAD stream version %d", s.header[0])
+	}
+	if err := s.init(key); err != nil {
+		return nil, err
+	}
+	// One byte more than a full chunk is read to tell whether another
+	// chunk follows.
+	s.buf = make([]byte, 0, AEADStreamChunkSize+gcmTagSize+1)
+	return s, nil
+}
+
+func (s *AEADStreamReader) Read(p []byte) (int, error) {
+	for len(s.out) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		if s.eof {
+			return 0, io.EOF
+		}
+		s.err = s.readChunk()
+	}
+	n := copy(p, s.out)
+	s.out = s.out[n:]
+	return n, nil
+}
+
+// readChunk reads, authenticates and decrypts the next chunk in place and
+// points out at its plaintext.
+func (s *AEADStreamReader) readChunk() error {
+	// The byte read ahead of the previous chunk starts this one.
+	if len(s.buf) > AEADStreamChunkSize+gcmTagSize {
+		s.buf = append(s.buf[:0], s.buf[AEADStreamChunkSize+gcmTagSize:]...)
+	}
+	n, err := io.ReadFull(s.r, s.buf[len(s.buf):cap(s.buf)])
+	s.buf = s.buf[:len(s.buf)+n]
+	last := false
+	switch err {
+	case nil:
+	case io.EOF, io.ErrUnexpectedEOF:
+		last = true
+	default:
+		return err
+	}
+	chunk := s.buf
+	if !last {
+		chunk = s.buf[:AEADStreamChunkSize+gcmTagSize]
+	}
+	if len(chunk) < gcmTagSize || last && len(chunk) == gcmTagSize && s.ctr > 0 {
+		return fmt.Errorf("truncated AEAD stream")
+	}
+
+	nonce, err := s.next(last)
+	if err != nil {
+		return err
+	}
+	out, err := s.aead.Open(chunk[:0], nonce, chunk, s.header[:])
+	if err != nil {
+		return err
+	}
+	s.out = out
+	s.eof = last
+	return nil
+}
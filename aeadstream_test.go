@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func aeadStreamSeal(t *testing.T, key, pt []byte) []byte {
+	var buf bytes.Buffer
+	w, err := NewAEADStreamWriter(&buf, key)
+	if err != nil {
+		t.Fatalf("NewAEADStreamWriter() = %s", err)
+	}
+	for in := pt; len(in) > 0; {
+		n := 1000
+		if n > len(in) {
+			n = len(in)
+		}
+		if _, err := w.Write(in[:n]); err != nil {
+			t.Fatalf("Write() = %s", err)
+		}
+		in = in[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() = %s", err)
+	}
+	return buf.Bytes()
+}
+
+func aeadStreamOpen(key, ct []byte) ([]byte, error) {
+	r, err := NewAEADStreamReader(bytes.NewReader(ct), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func aeadStreamInput(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i*7 + i>>8)
+	}
+	return b
+}
+
+func Test_AEADStream_RoundTrip(t *testing.T) {
+	const c = AEADStreamChunkSize
+	for _, key := range [][]byte{commonKey128, commonKey192, commonKey256} {
+		for _, n := range []int{0, 1, 64, c - 1, c, c + 1, 2 * c, 3*c + 17} {
+			pt := aeadStreamInput(n)
+			ct := aeadStreamSeal(t, key, pt)
+			chunks := (n + c - 1) / c
+			if chunks == 0 {
+				chunks = 1
+			}
+			if want := aeadStreamHeader + n + chunks*gcmTagSize; len(ct) != want {
+				t.Errorf("%d/%d: stream length %d, want %d", len(key), n, len(ct), want)
+			}
+
+			r, err := NewAEADStreamReader(iotest.HalfReader(bytes.NewReader(ct)), key)
+			if err != nil {
+				t.Fatalf("%d/%d: NewAEADStreamReader() = %s", len(key), n, err)
+			}
+			got, err := io.ReadAll(iotest.OneByteReader(r))
+			if err != nil || !bytes.Equal(got, pt) {
+				t.Errorf("%d/%d: decrypted %d bytes, %v, want %d bytes", len(key), n, len(got), err, n)
+			}
+		}
+	}
+}
+
+func Test_AEADStream_Tamper(t *testing.T) {
+	const c = AEADStreamChunkSize + gcmTagSize
+	pt := aeadStreamInput(3 * AEADStreamChunkSize)
+	ct := aeadStreamSeal(t, commonKey128, pt)
+	h, body := ct[:aeadStreamHeader], ct[aeadStreamHeader:]
+	chunk := func(i int) []byte { return body[i*c : (i+1)*c] }
+	join := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+
+	other := aeadStreamSeal(t, commonKey128, pt)
+
+	tests := map[string][]byte{
+		"truncated at chunk":   join(h, chunk(0), chunk(1)),
+		"truncated in chunk":   ct[:len(ct)-1],
+		"header only":          h,
+		"header cut":           h[:5],
+		"dropped chunk":        join(h, chunk(0), chunk(2)),
+		"swapped chunks":       join(h, chunk(1), chunk(0), chunk(2)),
+		"last chunk moved":     join(h, chunk(2), chunk(0), chunk(1)),
+		"appended chunk":       join(ct, chunk(1)),
+		"appended byte":        join(ct, []byte{0}),
+		"flipped bit":          join(h, chunk(0), chunk(1)[:10], []byte{chunk(1)[10] ^ 1}, chunk(1)[11:], chunk(2)),
+		"other salt":           join(other[:aeadStreamHeader], body),
+		"other stream's chunk": join(h, other[aeadStreamHeader:aeadStreamHeader+c], chunk(1), chunk(2)),
+		"empty last chunk":     join(h, chunk(0), chunk(1), chunk(2), aeadStreamSeal(t, commonKey128, nil)[aeadStreamHeader:]),
+	}
+	for name, ct := range tests {
+		r, err := NewAEADStreamReader(bytes.NewReader(ct), commonKey128)
+		if err != nil {
+			continue
+		}
+		got, err := io.ReadAll(r)
+		if err == nil {
+			t.Errorf("%s: stream accepted", name)
+		}
+		// Whatever was released must be a prefix of the plaintext, made of
+		// whole authenticated chunks.
+		if !bytes.HasPrefix(pt, got) || len(got)%AEADStreamChunkSize != 0 {
+			t.Errorf("%s: released %d bytes of unauthenticated plaintext", name, len(got))
+		}
+	}
+
+	if _, err := aeadStreamOpen(commonKey256, ct); err == nil {
+		t.Errorf("stream accepted under a different key")
+	}
+}
+
+func Test_AEADStream_Close(t *testing.T) {
+	sw := &shortWriter{n: 1 << 20}
+	w, err := NewAEADStreamWriter(sw, commonKey128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil || sw.closed != 1 {
+		t.Errorf("Close() = %v, closed %d times", err, sw.closed)
+	}
+	if _, err := w.Write([]byte{0}); err == nil {
+		t.Errorf("Write() after Close() succeeded")
+	}
+	if got, err := aeadStreamOpen(commonKey128, sw.Bytes()); err != nil || len(got) != 0 {
+		t.Errorf("empty stream = %x, %v", got, err)
+	}
+
+	if _, err := NewAEADStreamWriter(io.Discard, commonKey128[:15]); err == nil {
+		t.Errorf("NewAEADStreamWriter() accepted a 15-byte key")
+	}
+}
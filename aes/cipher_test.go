@@ -2,6 +2,7 @@ package aes
 
 import (
 	"bytes"
+	stdaes "crypto/aes"
 	"testing"
 )
 
@@ -172,3 +173,35 @@ func Test_aesCipher_Decrypt(t *testing.T) {
 		}
 	}
 }
+
+// FuzzCipher compares NewCipher with crypto/aes on random keys and blocks.
+// The seed corpus is in testdata/fuzz/FuzzCipher.
+func FuzzCipher(f *testing.F) {
+	for _, tt := range aesCipherTests {
+		f.Add(tt.key, tt.dec)
+	}
+	f.Fuzz(func(t *testing.T, key, src []byte) {
+		c, err := NewCipher(key)
+		want, wantErr := stdaes.NewCipher(key)
+		if (err != nil) != (wantErr != nil) {
+			t.Fatalf("NewCipher(%x) error = %v, crypto/aes error = %v", key, err, wantErr)
+		}
+		if err != nil {
+			return
+		}
+		for len(src) >= BlockSize {
+			got, exp := make([]byte, BlockSize), make([]byte, BlockSize)
+			c.Encrypt(got, src)
+			want.Encrypt(exp, src)
+			if !bytes.Equal(got, exp) {
+				t.Fatalf("key %x: Encrypt(%x) = %x, crypto/aes = %x", key, src[:BlockSize], got, exp)
+			}
+			c.Decrypt(got, src)
+			want.Decrypt(exp, src)
+			if !bytes.Equal(got, exp) {
+				t.Fatalf("key %x: Decrypt(%x) = %x, crypto/aes = %x", key, src[:BlockSize], got, exp)
+			}
+			src = src[BlockSize:]
+		}
+	})
+}
@@ -0,0 +1,237 @@
+// RFC 9106: Argon2 Memory-Hard Function for Password Hashing and Proof-of-Work Applications
+// https://www.rfc-editor.org/rfc/rfc9106
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"sync"
+)
+
+const (
+	argon2Version = 0x13
+	argon2id      = 2
+
+	argon2SyncPoints = 4   // slices of each pass over a lane
+	argon2BlockWords = 128 // 1 KiB blocks
+)
+
+type argon2Block [argon2BlockWords]uint64
+
+// argon2idKey derives a key of keyLen bytes from password and salt with
+// Argon2id version 1.3, which makes time passes over memory KiB in threads
+// lanes. secret and data are the optional secret value and associated
+// data.
+func argon2idKey(password, salt, secret, data []byte, time, memory, threads, keyLen int) ([]byte, error) {
+	if time < 1 || threads < 1 || threads > 1<<24-1 || memory < 8*threads || uint64(memory) > 1<<32-1 || keyLen < 4 || uint64(keyLen) > 1<<32-1 {
+		return nil, fmt.Errorf("argon2: invalid parameters")
+	}
+
+	h := newBLAKE2b(blake2bSize)
+	var b [4]byte
+	for _, v := range []int{threads, keyLen, memory, time, argon2Version, argon2id} {
+		binary.LittleEndian.PutUint32(b[:], uint32(v))
+		h.Write(b[:])
+	}
+	for _, s := range [][]byte{password, salt, secret, data} {
+		binary.LittleEndian.PutUint32(b[:], uint32(len(s)))
+		h.Write(b[:])
+		h.Write(s)
+	}
+	h0 := h.Sum(make([]byte, 0, blake2bSize+8))[:blake2bSize+8]
+
+	// The memory is rounded down to whole segments.
+	laneLen := memory / (argon2SyncPoints * threads) * argon2SyncPoints
+	mem := make([]argon2Block, laneLen*threads)
+	var buf [8 * argon2BlockWords]byte
+	for lane := 0; lane < threads; lane++ {
+		binary.LittleEndian.PutUint32(h0[blake2bSize+4:], uint32(lane))
+		for i := 0; i < 2; i++ {
+			binary.LittleEndian.PutUint32(h0[blake2bSize:], uint32(i))
+			argon2Hash(buf[:], h0)
+			for j := range mem[lane*laneLen+i] {
+				mem[lane*laneLen+i][j] = binary.LittleEndian.Uint64(buf[8*j:])
+			}
+		}
+	}
+
+	// The lanes of a slice are independent of each other.
+	var wg sync.WaitGroup
+	for pass := 0; pass < time; pass++ {
+		for slice := 0; slice < argon2SyncPoints; slice++ {
+			wg.Add(threads)
+			for lane := 0; lane < threads; lane++ {
+				go func(lane int) {
+					argon2Segment(mem, pass, slice, lane, threads, laneLen, time)
+					wg.Done()
+				}(lane)
+			}
+			wg.Wait()
+		}
+	}
+
+	final := mem[laneLen-1]
+	for lane := 1; lane < threads; lane++ {
+		for i, v := range mem[(lane+1)*laneLen-1] {
+			final[i] ^= v
+		}
+	}
+	for i, v := range final {
+		binary.LittleEndian.PutUint64(buf[8*i:], v)
+	}
+	key := make([]byte, keyLen)
+	argon2Hash(key, buf[:])
+	return key, nil
+}
+
+// argon2Segment fills the blocks of a segment: the blocks of lane in the
+// given slice of a pass. Argon2id takes the reference blocks from a
+// data-independent sequence in the first half of the first pass and from
+// the previous block otherwise.
+func argon2Segment(mem []argon2Block, pass, slice, lane, lanes, laneLen, time int) {
+	segLen := laneLen / argon2SyncPoints
+	dataIndependent := pass == 0 && slice < argon2SyncPoints/2
+	var addresses, input, zero argon2Block
+	nextAddresses := func() {
+		input[6]++
+		argon2Compress(&addresses, &zero, &input, false)
+		argon2Compress(&addresses, &zero, &addresses, false)
+	}
+	if dataIndependent {
+		copy(input[:], []uint64{uint64(pass), uint64(lane), uint64(slice), uint64(len(mem)), uint64(time), argon2id})
+	}
+
+	start := 0
+	if pass == 0 && slice == 0 {
+		// The first two blocks of each lane come from H0.
+		start = 2
+		if dataIndependent {
+			nextAddresses()
+		}
+	}
+	for i := start; i < segLen; i++ {
+		cur := lane*laneLen + slice*segLen + i
+		prev := cur - 1
+		if cur%laneLen == 0 {
+			prev += laneLen
+		}
+		var rand uint64
+		if dataIndependent {
+			if i%argon2BlockWords == 0 {
+				nextAddresses()
+			}
+			rand = addresses[i%argon2BlockWords]
+		} else {
+			rand = mem[prev][0]
+		}
+
+		// The reference lane, and the blocks that may be referenced: those
+		// of the last three finished slices, or of the finished slices of
+		// the first pass, and in the same lane those of this segment but
+		// the previous block.
+		refLane := int(rand>>32) % lanes
+		if pass == 0 && slice == 0 {
+			refLane = lane
+		}
+		var area, areaStart int
+		if pass == 0 {
+			area = slice * segLen
+		} else {
+			area = (argon2SyncPoints - 1) * segLen
+			areaStart = (slice + 1) % argon2SyncPoints * segLen
+		}
+		if refLane == lane {
+			area += i - 1
+		} else if i == 0 {
+			area--
+		}
+		x := rand & 0xffffffff
+		x = x * x >> 32
+		x = uint64(area) - 1 - uint64(area)*x>>32
+		ref := refLane*laneLen + (areaStart+int(x))%laneLen
+
+		argon2Compress(&mem[cur], &mem[prev], &mem[ref], pass > 0)
+	}
+}
+
+// argon2Compress sets out to G(x, y), or XORs G(x, y) into it.
+func argon2Compress(out, x, y *argon2Block, xor bool) {
+	var r, q argon2Block
+	for i := range r {
+		r[i] = x[i] ^ y[i]
+	}
+	q = r
+	// The permutation P on the eight rows of 16 words, then on the eight
+	// columns of pairs of words.
+	for i := 0; i < 8; i++ {
+		argon2Permute(&q, 16*i, 16*i+1, 16*i+2, 16*i+3, 16*i+4, 16*i+5, 16*i+6, 16*i+7,
+			16*i+8, 16*i+9, 16*i+10, 16*i+11, 16*i+12, 16*i+13, 16*i+14, 16*i+15)
+	}
+	for i := 0; i < 8; i++ {
+		argon2Permute(&q, 2*i, 2*i+1, 2*i+16, 2*i+17, 2*i+32, 2*i+33, 2*i+48, 2*i+49,
+			2*i+64, 2*i+65, 2*i+80, 2*i+81, 2*i+96, 2*i+97, 2*i+112, 2*i+113)
+	}
+	for i := range out {
+		if xor {
+			out[i] ^= q[i] ^ r[i]
+		} else {
+			out[i] = q[i] ^ r[i]
+		}
+	}
+}
+
+// argon2Permute applies the round function of BLAKE2b, with the
+// multiplications of BlaMka, to the 16 words of b at the given indexes.
+func argon2Permute(b *argon2Block, i0, i1, i2, i3, i4, i5, i6, i7, i8, i9, i10, i11, i12, i13, i14, i15 int) {
+	v := [16]uint64{b[i0], b[i1], b[i2], b[i3], b[i4], b[i5], b[i6], b[i7], b[i8], b[i9], b[i10], b[i11], b[i12], b[i13], b[i14], b[i15]}
+	g := func(a, b, c, d int) {
+		v[a] += v[b] + 2*uint64(uint32(v[a]))*uint64(uint32(v[b]))
+		v[d] = bits.RotateLeft64(v[d]^v[a], -32)
+		v[c] += v[d] + 2*uint64(uint32(v[c]))*uint64(uint32(v[d]))
+		v[b] = bits.RotateLeft64(v[b]^v[c], -24)
+		v[a] += v[b] + 2*uint64(uint32(v[a]))*uint64(uint32(v[b]))
+		v[d] = bits.RotateLeft64(v[d]^v[a], -16)
+		v[c] += v[d] + 2*uint64(uint32(v[c]))*uint64(uint32(v[d]))
+		v[b] = bits.RotateLeft64(v[b]^v[c], -63)
+	}
+	g(0, 4, 8, 12)
+	g(1, 5, 9, 13)
+	g(2, 6, 10, 14)
+	g(3, 7, 11, 15)
+	g(0, 5, 10, 15)
+	g(1, 6, 11, 12)
+	g(2, 7, 8, 13)
+	g(3, 4, 9, 14)
+	b[i0], b[i1], b[i2], b[i3], b[i4], b[i5], b[i6], b[i7] = v[0], v[1], v[2], v[3], v[4], v[5], v[6], v[7]
+	b[i8], b[i9], b[i10], b[i11], b[i12], b[i13], b[i14], b[i15] = v[8], v[9], v[10], v[11], v[12], v[13], v[14], v[15]
+}
+
+// argon2Hash fills out with the variable-length hash H' of in: BLAKE2b of
+// len(out) || in if out is at most 64 bytes, and otherwise the first halves
+// of a chain of BLAKE2b-512 hashes, the last one of the length left.
+func argon2Hash(out, in []byte) {
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(out)))
+	size := len(out)
+	if size > blake2bSize {
+		size = blake2bSize
+	}
+	h := newBLAKE2b(size)
+	h.Write(n[:])
+	h.Write(in)
+	v := h.Sum(nil)
+	for len(out) > blake2bSize {
+		copy(out, v[:32])
+		out = out[32:]
+		size := len(out)
+		if size > blake2bSize {
+			size = blake2bSize
+		}
+		h = newBLAKE2b(size)
+		h.Write(v)
+		v = h.Sum(v[:0])
+	}
+	copy(out, v)
+}
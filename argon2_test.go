@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+var argon2idTests = []struct {
+	password, salt, secret, data []byte
+	time, memory, threads        int
+	key                          string
+}{
+	// RFC 9106, Section 5.3.
+	{
+		bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 16), bytes.Repeat([]byte{3}, 8), bytes.Repeat([]byte{4}, 12),
+		3, 32, 4, "0d640df58d78766c08c037a34a8b53c9d01ef0452d75b65eb52520e96b01e659",
+	},
+	// The others are from IDKey of golang.org/x/crypto/argon2 v0.54.0:
+	// several address blocks, memory that is not a multiple of the lanes,
+	// and keys of more or less than 64 bytes.
+	{[]byte("password"), []byte("somesalt"), nil, nil, 2, 1024, 3, "7641ca5b9b15721072d53281aa9aec6da0d69e99ca1a9d67802f2da60c12294d13a12581e9694a349a3f4dc39318b021374f4c8cdfb3312d1b0e31de2c67dfeee548d8a5dc0b4c1216659fc978061dc79837ce2b4bac7a03b8896b1838c9f330afddfbf6"},
+	{nil, []byte("saltsalt"), nil, nil, 1, 8, 1, "477bbdbc"},
+	{[]byte("pw"), []byte("0123456789abcdef"), nil, nil, 3, 4096, 2, "7ef6bcdb69dc135e874f2e75e90c37ffc415e59f305ad7e8da8d3b079e0e084e"},
+	{[]byte("correct horse"), []byte("0123456789abcdef"), nil, nil, 1, 1000, 4, "bd0b191c780282040691f05f8b27a10851ea9734e12fd75745bfb5c1e46d1c0f5fe34e7cc522e6220ba83de23914a2af51783f1db904e3f09a7f6241f8f5408e"},
+}
+
+func Test_argon2idKey(t *testing.T) {
+	for i, tt := range argon2idTests {
+		want, _ := hex.DecodeString(tt.key)
+		got, err := argon2idKey(tt.password, tt.salt, tt.secret, tt.data, tt.time, tt.memory, tt.threads, len(want))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("#%d: argon2idKey() = %x, %v, want %x", i, got, err, want)
+		}
+	}
+	for i, tt := range []struct{ time, memory, threads, keyLen int }{
+		{0, 64, 1, 32},
+		{1, 64, 0, 32},
+		{1, 15, 2, 32},
+		{1, 64, 1, 3},
+	} {
+		if _, err := argon2idKey(nil, nil, nil, nil, tt.time, tt.memory, tt.threads, tt.keyLen); err == nil {
+			t.Errorf("#%d: argon2idKey() succeeded", i)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"runtime"
+	"time"
+)
+
+// benchSizes are the buffer sizes of the CTR benchmarks.
+var benchSizes = []struct {
+	name string
+	n    int
+}{
+	{"64", 64}, {"1K", 1 << 10}, {"16K", 16 << 10},
+}
+
+type benchmark struct {
+	name  string
+	bytes int         // bytes processed by each operation, or 0
+	f     func(n int) // runs n operations
+}
+
+// benchmarks returns the benchmarks of key setup, single-block Encrypt and
+// Decrypt, and CTR encryption at each of benchSizes for each cipher. They
+// are run by "go test -bench ." and by the bench command.
+func benchmarks() []benchmark {
+	var bms []benchmark
+	for _, c := range []struct {
+		name string
+		alg  string
+		key  int
+	}{
+		{"aes-128", "aes", 16}, {"aes-192", "aes", 24}, {"aes-256", "aes", 32},
+		{"des", "des", 8}, {"3des", "3des", 24},
+	} {
+		alg, key := c.alg, make([]byte, c.key)
+		block, err := newBlock(alg, key)
+		if err != nil {
+			panic(err)
+		}
+		bs := block.BlockSize()
+		buf := make([]byte, bs)
+		bms = append(bms,
+			benchmark{c.name + "/NewCipher", 0, func(n int) {
+				for i := 0; i < n; i++ {
+					newBlock(alg, key)
+				}
+			}},
+			benchmark{c.name + "/Encrypt", bs, func(n int) {
+				for i := 0; i < n; i++ {
+					block.Encrypt(buf, buf)
+				}
+			}},
+			benchmark{c.name + "/Decrypt", bs, func(n int) {
+				for i := 0; i < n; i++ {
+					block.Decrypt(buf, buf)
+				}
+			}})
+		for _, size := range benchSizes {
+			buf := make([]byte, size.n)
+			ctr := NewCTR(block, make([]byte, bs))
+			bms = append(bms, benchmark{c.name + "/CTR/" + size.name, size.n, func(n int) {
+				for i := 0; i < n; i++ {
+					ctr.XORKeyStream(buf, buf)
+				}
+			}})
+		}
+	}
+	return bms
+}
+
+// benchResult is the last run of a benchmark.
+type benchResult struct {
+	n       int
+	elapsed time.Duration
+	allocs  uint64 // total over the n operations
+	alloced uint64
+}
+
+// run runs bm with a growing number of operations until a run takes at
+// least d, as go test does.
+func (bm *benchmark) run(d time.Duration) benchResult {
+	var r benchResult
+	for n := 1; ; {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		bm.f(n)
+		elapsed := time.Since(start)
+		runtime.ReadMemStats(&after)
+		r = benchResult{n, elapsed, after.Mallocs - before.Mallocs, after.TotalAlloc - before.TotalAlloc}
+		if elapsed >= d || n >= 1e9 {
+			return r
+		}
+
+		// Aim 20% past d from the last rate, growing at most 100 times.
+		next := 100 * n
+		if elapsed > 0 {
+			if m := int(1.2 * float64(n) * float64(d) / float64(elapsed)); m < next {
+				next = m
+			}
+		}
+		if next <= n {
+			next = n + 1
+		}
+		n = next
+	}
+}
+
+func benchCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: go-crypto bench [-run regexp] [-benchtime d]\n")
+		fs.PrintDefaults()
+	}
+	filter := fs.String("run", "", "run only the benchmarks whose names match the regular expression")
+	benchtime := fs.Duration("benchtime", time.Second, "run each benchmark for about this long")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	re, err := regexp.Compile(*filter)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "%-20s %12s %10s %10s %10s\n", "benchmark", "ns/op", "MB/s", "allocs/op", "B/op")
+	for _, bm := range benchmarks() {
+		if !re.MatchString(bm.name) {
+			continue
+		}
+		r := bm.run(*benchtime)
+		mbs := "-"
+		if bm.bytes > 0 && r.elapsed > 0 {
+			mbs = fmt.Sprintf("%.2f", float64(bm.bytes)*float64(r.n)/1e6/r.elapsed.Seconds())
+		}
+		n := uint64(r.n)
+		fmt.Fprintf(stdout, "%-20s %12d %10s %10d %10d\n", bm.name, r.elapsed.Nanoseconds()/int64(r.n), mbs, r.allocs/n, r.alloced/n)
+	}
+	return nil
+}
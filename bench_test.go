@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func BenchmarkCiphers(b *testing.B) {
+	for _, bm := range benchmarks() {
+		bm := bm
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetBytes(int64(bm.bytes))
+			b.ReportAllocs()
+			bm.f(b.N)
+		})
+	}
+}
+
+func Test_run_bench(t *testing.T) {
+	out, errOut, code := runCommand(nil, "bench", "-run", "^des/(Encrypt|CTR/64)$", "-benchtime", "10ms")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if code != 0 || len(lines) != 3 || !strings.HasPrefix(lines[1], "des/Encrypt ") || !strings.HasPrefix(lines[2], "des/CTR/64 ") {
+		t.Fatalf("bench = %q, %q, %d", out, errOut, code)
+	}
+	if fields := strings.Fields(lines[1]); len(fields) != 5 || fields[2] == "-" {
+		t.Errorf("bench row %q, want name, ns/op, MB/s, allocs/op and B/op", lines[1])
+	}
+	if _, _, code := runCommand(nil, "bench", "-run", "("); code != 1 {
+		t.Errorf("bench -run ( exit status %d, want 1", code)
+	}
+}
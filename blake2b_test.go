@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+var blake2bTests = []struct {
+	in   []byte
+	size int
+	sum  string
+}{
+	// RFC 7693, Appendix A.
+	{[]byte("abc"), 64, "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"},
+	// The others are from Python's hashlib.blake2b: a block that is the
+	// last, several blocks, and truncated digests.
+	{nil, 64, "786a02f742015903c6c6fd852552d272912f4740e15847618a86e217f71f5419d25e1031afee585313896444934eb04b903a685b1448b755d56f701afe9be2ce"},
+	{make([]byte, 128), 64, "865939e120e6805438478841afb739ae4250cf372653078a065cdcfffca4caf798e6d462b65d658fc165782640eded70963449ae1500fb0f24981d7727e22c41"},
+	{bytes.Repeat(blake2bCounting(256), 3), 64, "323e97a7a859ee63c9013debb0ca995811e73117a2f574723416e596ebc184e37a59b66d2f597df4a7c1b0d1d41a1a7f28774f46a6864d56c57b9d6c5f7302fb"},
+	{[]byte("abc"), 32, "bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319"},
+	{blake2bCounting(255), 17, "939e0315eab5f42b8337084dc235fa0b94"},
+}
+
+// blake2bCounting returns the bytes 0, 1, ..., n-1.
+func blake2bCounting(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+func Test_blake2b(t *testing.T) {
+	for i, tt := range blake2bTests {
+		want, _ := hex.DecodeString(tt.sum)
+		h := newBLAKE2b(tt.size)
+		h.Write(tt.in)
+		if got := h.Sum(nil); !bytes.Equal(got, want) {
+			t.Errorf("#%d: Sum() = %x, want %x", i, got, want)
+		}
+
+		// In pieces of 1, 4, 7, ... bytes, after a Reset.
+		h.Write([]byte("x"))
+		h.Reset()
+		for in, n := tt.in, 1; len(in) > 0; n += 3 {
+			if n > len(in) {
+				n = len(in)
+			}
+			h.Write(in[:n])
+			in = in[n:]
+		}
+		if got := h.Sum([]byte{0}); !bytes.Equal(got[1:], want) {
+			t.Errorf("#%d: Sum() of pieces = %x, want %x", i, got[1:], want)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+// The Advanced Encryption Standard Algorithm Validation Suite (AESAVS)
+// https://csrc.nist.gov/CSRC/media/Projects/Cryptographic-Algorithm-Validation-Program/documents/aes/AESAVS.pdf
+//
+// NIST SP 800-20: Modes of Operation Validation System for the Triple Data Encryption Algorithm (TMOVS)
+// https://csrc.nist.gov/publications/detail/sp/800-20/final
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AirWSW/go-crypto/registry"
+)
+
+// cavpRecord is one test vector of a CAVP response file.
+type cavpRecord struct {
+	section string
+	params  map[string]string
+	line    int
+	fields  map[string]string
+	values  map[string][]string
+}
+
+// parseCAVP reads the test vectors of a CAVP response file: groups of
+// "NAME = value" lines separated by blank lines, under section headers such
+// as [ENCRYPT]. Comment lines start with '#'. Headers of the form
+// [NAME = value], of which a group of vectors may have several, are
+// collected in the params of the records that follow instead. A name may
+// repeat within a record, as AdditionalInput does in DRBG vectors: fields
+// holds its last value and values all of them in order.
+func parseCAVP(r io.Reader) ([]cavpRecord, error) {
+	var records []cavpRecord
+	section := ""
+	params := make(map[string]string)
+	inRecord, inHeaders := false, false
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "":
+			inRecord = false
+		case line[0] == '#':
+		case line[0] == '[':
+			if line[len(line)-1] != ']' {
+				return nil, fmt.Errorf("line %d: malformed section header", n)
+			}
+			if !inHeaders {
+				params = make(map[string]string)
+				inHeaders = true
+			}
+			header := line[1 : len(line)-1]
+			if i := strings.IndexByte(header, '='); i >= 0 {
+				params[strings.TrimSpace(header[:i])] = strings.TrimSpace(header[i+1:])
+			} else {
+				section = header
+			}
+			inRecord = false
+		default:
+			i := strings.IndexByte(line, '=')
+			if i < 0 {
+				return nil, fmt.Errorf("line %d: missing '='", n)
+			}
+			if !inRecord {
+				records = append(records, cavpRecord{
+					section: section,
+					params:  params,
+					line:    n,
+					fields:  make(map[string]string),
+					values:  make(map[string][]string),
+				})
+				inRecord, inHeaders = true, false
+			}
+			rec := &records[len(records)-1]
+			name, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+			rec.fields[name] = value
+			rec.values[name] = append(rec.values[name], value)
+		}
+	}
+	return records, sc.Err()
+}
+
+// cavpSuite describes the tests of a response file, as told by its name,
+// such as ECBGFSbox128.rsp, CBCMMT256.rsp or TCBCMMT2.rsp.
+type cavpSuite struct {
+	alg  string // "aes" or "3des"
+	mode string // "ecb", "cbc", "cfb8", "cfb", "ofb" or "ctr"
+	mct  bool
+}
+
+var cavpModes = []struct {
+	prefix string
+	mode   string
+}{
+	// Longer prefixes come first. An empty mode is not supported.
+	{"CBCI", ""}, {"OFBI", ""}, {"CFP", ""}, {"CFB128", "cfb"}, {"CFB64", "cfb"},
+	{"CFB8", "cfb8"}, {"CFB1", ""}, {"ECB", "ecb"}, {"CBC", "cbc"}, {"OFB", "ofb"}, {"CTR", "ctr"},
+}
+
+func parseCAVPSuite(file string) (cavpSuite, error) {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	s := cavpSuite{alg: "aes"}
+	if strings.HasPrefix(name, "T") {
+		s.alg = "3des"
+		name = name[1:]
+	}
+	for _, m := range cavpModes {
+		if !strings.HasPrefix(name, m.prefix) {
+			continue
+		}
+		if m.mode == "" {
+			return s, fmt.Errorf("mode %s is not supported", m.prefix)
+		}
+		s.mode = m.mode
+		s.mct = strings.Contains(name, "MCT") || strings.Contains(name, "Monte")
+		return s, nil
+	}
+	return s, fmt.Errorf("cannot tell the mode from the file name")
+}
+
+// cavpField returns the named field of r decoded from hex.
+func cavpField(r cavpRecord, name string) ([]byte, error) {
+	v, ok := r.fields[name]
+	if !ok {
+		return nil, fmt.Errorf("missing %s", name)
+	}
+	b, err := hex.DecodeString(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", name, err)
+	}
+	return b, nil
+}
+
+// key returns the key of r. TDES vectors give one key as KEYs for all three
+// or the three keys as KEY1, KEY2 and KEY3.
+func (s cavpSuite) key(r cavpRecord) ([]byte, error) {
+	if s.alg == "aes" {
+		return cavpField(r, "KEY")
+	}
+	if _, ok := r.fields["KEYs"]; ok {
+		k, err := cavpField(r, "KEYs")
+		return bytes.Repeat(k, 3), err
+	}
+	var key []byte
+	for _, name := range []string{"KEY1", "KEY2", "KEY3"} {
+		k, err := cavpField(r, name)
+		if err != nil {
+			return nil, err
+		}
+		key = append(key, k...)
+	}
+	return key, nil
+}
+
+// cavpVector holds the inputs and the expected output of a test vector.
+type cavpVector struct {
+	decrypt bool
+	key     []byte
+	iv      []byte
+	in      []byte
+	want    []byte
+}
+
+func (s cavpSuite) vector(r cavpRecord) (*cavpVector, error) {
+	v := new(cavpVector)
+	switch r.section {
+	case "ENCRYPT":
+	case "DECRYPT":
+		v.decrypt = true
+	default:
+		return nil, fmt.Errorf("unknown section [%s]", r.section)
+	}
+	inName, outName := "PLAINTEXT", "CIPHERTEXT"
+	if v.decrypt {
+		inName, outName = outName, inName
+	}
+
+	var err error
+	if v.key, err = s.key(r); err != nil {
+		return nil, err
+	}
+	if s.mode != "ecb" {
+		if v.iv, err = cavpField(r, "IV"); err != nil {
+			return nil, err
+		}
+	}
+	if v.in, err = cavpField(r, inName); err != nil {
+		return nil, err
+	}
+	if v.want, err = cavpField(r, outName); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// run computes the output of the known-answer or multi-block message test
+// v.
+func (s cavpSuite) run(v *cavpVector) ([]byte, error) {
+	block, err := newBlock(s.alg, v.key)
+	if err != nil {
+		return nil, err
+	}
+	if s.mode != "ecb" && len(v.iv) != block.BlockSize() {
+		return nil, fmt.Errorf("invalid IV length")
+	}
+	return cavpCrypt(block, s.mode, v.iv, v.in, v.decrypt)
+}
+
+// cavpCrypt encrypts or decrypts in with block in the given mode, without
+// padding.
+func cavpCrypt(block cipher.Block, mode string, iv, in []byte, decrypt bool) ([]byte, error) {
+	bm, s, err := registry.NewMode(block, mode, iv, decrypt)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(in))
+	if bm != nil {
+		if len(in)%bm.BlockSize() != 0 {
+			return nil, fmt.Errorf("input is not a multiple of the block size")
+		}
+		bm.CryptBlocks(out, in)
+	} else {
+		s.XORKeyStream(out, in)
+	}
+	return out, nil
+}
+
+func cavpCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("cavp", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: go-crypto cavp [-q] file.rsp ...\n")
+		fs.PrintDefaults()
+	}
+	quiet := fs.Bool("q", false, "report only failures and the summary")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	var pass, fail, skip int
+	for _, file := range fs.Args() {
+		name := filepath.Base(file)
+		suite, err := parseCAVPSuite(file)
+		if err != nil {
+			fmt.Fprintf(stdout, "%s: SKIP %s\n", name, err)
+			skip++
+			continue
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		records, err := parseCAVP(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		// next holds the inputs of the next Monte Carlo test, which follow
+		// from the previous one in the same section.
+		var next *cavpVector
+		section := ""
+		for _, r := range records {
+			count, ok := r.fields["COUNT"]
+			if !ok {
+				continue
+			}
+			if r.section != section {
+				next, section = nil, r.section
+			}
+			id := fmt.Sprintf("%s [%s] COUNT = %s", name, r.section, count)
+			v, err := suite.vector(r)
+			var got []byte
+			if err == nil && suite.mct {
+				if next != nil && !next.sameInputs(v) {
+					err = fmt.Errorf("inputs do not follow from the previous vector")
+				} else {
+					got, next, err = suite.monteCarlo(v)
+				}
+			} else if err == nil {
+				got, err = suite.run(v)
+			}
+			switch {
+			case err != nil:
+				fmt.Fprintf(stdout, "%s: FAIL line %d: %s\n", id, r.line, err)
+				fail++
+			case !bytes.Equal(got, v.want):
+				fmt.Fprintf(stdout, "%s: FAIL got %x, want %x\n", id, got, v.want)
+				fail++
+			default:
+				if !*quiet {
+					fmt.Fprintf(stdout, "%s: PASS\n", id)
+				}
+				pass++
+			}
+		}
+	}
+	fmt.Fprintf(stdout, "%d passed, %d failed, %d skipped\n", pass, fail, skip)
+	if fail > 0 {
+		return fmt.Errorf("%d vectors failed", fail)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_parseCAVP(t *testing.T) {
+	in := `# CAVS 11.1
+# AESVS GFSbox test data for ECB
+
+[ENCRYPT]
+
+COUNT = 0
+KEY = 00000000000000000000000000000000
+PLAINTEXT = f34481ec3cc627bacd5dc3fb08f273e6
+CIPHERTEXT = 0336763e966d92595a567cc9ce537f5e
+
+[DECRYPT]
+COUNT = 0
+CIPHERTEXT=0336763e966d92595a567cc9ce537f5e
+CIPHERTEXT = 00
+`
+	records, err := parseCAVP(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("parseCAVP() = %s", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("parseCAVP() = %d records, want 2", len(records))
+	}
+	if r := records[0]; r.section != "ENCRYPT" || r.line != 6 || len(r.fields) != 4 || r.fields["PLAINTEXT"] != "f34481ec3cc627bacd5dc3fb08f273e6" {
+		t.Errorf("records[0] = %+v", r)
+	}
+	if r := records[1]; r.section != "DECRYPT" || r.fields["CIPHERTEXT"] != "00" || len(r.values["CIPHERTEXT"]) != 2 || r.values["CIPHERTEXT"][0] != "0336763e966d92595a567cc9ce537f5e" {
+		t.Errorf("records[1] = %+v", r)
+	}
+
+	for _, bad := range []string{"[ENCRYPT\n", "COUNT 0\n"} {
+		if _, err := parseCAVP(strings.NewReader(bad)); err == nil {
+			t.Errorf("parseCAVP(%q) succeeded", bad)
+		}
+	}
+}
+
+// Headers of the form [NAME = value] are params of the records up to the
+// next group of headers, and do not change the section.
+func Test_parseCAVP_params(t *testing.T) {
+	in := `[ENCRYPT]
+[PRF=CMAC_AES128]
+[RLEN = 8_BITS]
+
+COUNT=0
+
+COUNT=1
+
+[PRF=CMAC_TDES2]
+COUNT=0
+
+[DECRYPT]
+COUNT=0
+`
+	records, err := parseCAVP(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("parseCAVP() = %s", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("parseCAVP() = %d records, want 4", len(records))
+	}
+	for i, want := range []struct {
+		section string
+		params  map[string]string
+	}{
+		{"ENCRYPT", map[string]string{"PRF": "CMAC_AES128", "RLEN": "8_BITS"}},
+		{"ENCRYPT", map[string]string{"PRF": "CMAC_AES128", "RLEN": "8_BITS"}},
+		{"ENCRYPT", map[string]string{"PRF": "CMAC_TDES2"}},
+		{"DECRYPT", map[string]string{}},
+	} {
+		if r := records[i]; r.section != want.section || !reflect.DeepEqual(r.params, want.params) {
+			t.Errorf("records[%d] = %q, %v, want %q, %v", i, r.section, r.params, want.section, want.params)
+		}
+	}
+}
+
+func Test_parseCAVPSuite(t *testing.T) {
+	tests := []struct {
+		name string
+		want cavpSuite
+	}{
+		{"ECBGFSbox128.rsp", cavpSuite{"aes", "ecb", false}},
+		{"dir/CBCMMT256.rsp", cavpSuite{"aes", "cbc", false}},
+		{"CFB8VarTxt192.rsp", cavpSuite{"aes", "cfb8", false}},
+		{"CFB128MCT128.rsp", cavpSuite{"aes", "cfb", true}},
+		{"OFBMMT128.rsp", cavpSuite{"aes", "ofb", false}},
+		{"TECBMonte1.rsp", cavpSuite{"3des", "ecb", true}},
+		{"TCFB64invperm.rsp", cavpSuite{"3des", "cfb", false}},
+	}
+	for _, tt := range tests {
+		if got, err := parseCAVPSuite(tt.name); err != nil || got != tt.want {
+			t.Errorf("parseCAVPSuite(%q) = %+v, %v, want %+v", tt.name, got, err, tt.want)
+		}
+	}
+	for _, name := range []string{"CFB1MMT128.rsp", "TCBCIMMT1.rsp", "TCFP8MMT1.rsp", "XTSGenAES128.rsp"} {
+		if got, err := parseCAVPSuite(name); err == nil {
+			t.Errorf("parseCAVPSuite(%q) = %+v, want error", name, got)
+		}
+	}
+}
+
+func Test_cavpCommand(t *testing.T) {
+	all, err := filepath.Glob(filepath.Join("testdata", "modes", "*.rsp"))
+	if err != nil || len(all) == 0 {
+		t.Fatalf("no response files: %v", err)
+	}
+	var files []string
+	for _, f := range all {
+		if s, _ := parseCAVPSuite(f); !s.mct {
+			files = append(files, f)
+		}
+	}
+	out, errOut, code := runCommand(nil, append([]string{"cavp"}, files...)...)
+	if code != 0 || strings.Contains(out, "FAIL") || strings.Contains(out, "SKIP") {
+		t.Errorf("cavp exited with %d: %s%s", code, out, errOut)
+	}
+	if !strings.Contains(out, "ECBGFSbox128.rsp [DECRYPT] COUNT = 6: PASS\n") {
+		t.Errorf("cavp output does not report each vector:\n%s", out)
+	}
+}
+
+func Test_cavpCommand_Fail(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "modes", "TCBCMMT3.rsp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "TCBCMMT3.rsp")
+	// Change the first byte of the first ciphertext, and drop the IV of
+	// the second vector.
+	s := strings.Replace(string(b), "CIPHERTEXT = ", "CIPHERTEXT = 00", 1)
+	i := strings.Index(s, "COUNT = 1")
+	j := i + strings.Index(s[i:], "IV = ")
+	s = s[:j] + "X" + s[j:]
+	os.WriteFile(bad, []byte(s), 0o600)
+	skipped := filepath.Join(dir, "CFB1MMT128.rsp")
+	os.WriteFile(skipped, nil, 0o600)
+
+	out, errOut, code := runCommand(nil, "cavp", "-q", bad, skipped)
+	if code != 1 || !strings.Contains(errOut, "2 vectors failed") {
+		t.Errorf("cavp = %q, %d, want exit 1", errOut, code)
+	}
+	for _, want := range []string{
+		"TCBCMMT3.rsp [ENCRYPT] COUNT = 0: FAIL got ",
+		"TCBCMMT3.rsp [ENCRYPT] COUNT = 1: FAIL line ",
+		"CFB1MMT128.rsp: SKIP mode CFB1 is not supported",
+		"18 passed, 2 failed, 1 skipped",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("cavp output does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "PASS") {
+		t.Errorf("cavp -q reported passing vectors:\n%s", out)
+	}
+
+	if _, _, code := runCommand(nil, "cavp"); code != 2 {
+		t.Errorf("cavp without files exited with %d, want 2", code)
+	}
+}
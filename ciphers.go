@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/AirWSW/go-crypto/registry"
+)
+
+func ciphersCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("ciphers", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: go-crypto ciphers\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errUsage
+	}
+	fmt.Fprintf(stdout, "%-16s %4s %4s %6s\n", "name", "key", "iv", "block")
+	for _, name := range registry.CipherNames() {
+		c, err := registry.LookupCipher(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%-16s %4d %4d %6d\n", c.Name, c.KeySize, c.IVSize, c.BlockSize)
+	}
+	return nil
+}
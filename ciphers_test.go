@@ -0,0 +1,13 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_run_ciphers(t *testing.T) {
+	out, errOut, code := runCommand(nil, "ciphers")
+	if code != 0 || !strings.Contains(out, "\ndes-ede3-cbc       24    8      8\n") {
+		t.Errorf("ciphers = %q, %q, %d", out, errOut, code)
+	}
+}
@@ -0,0 +1,101 @@
+// NIST SP 800-38B: Recommendation for Block Cipher Modes of Operation: The CMAC Mode for Authentication
+// https://csrc.nist.gov/publications/detail/sp/800-38b/final
+
+package main
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"fmt"
+)
+
+// CMAC is the cipher-based message authentication code of SP 800-38B over a
+// 64-bit or 128-bit block cipher, such as DES, 3DES or AES. It implements
+// hash.Hash and its Sum is the full block-size tag.
+type CMAC struct {
+	block  cipher.Block
+	k1, k2 []byte
+	x      []byte // the chaining value
+	buf    []byte // the last, possibly partial, block written
+	n      int
+}
+
+// NewCMAC returns a CMAC keyed by the given Block, whose block size must be
+// 8 or 16 bytes.
+func NewCMAC(block cipher.Block) (*CMAC, error) {
+	bs := block.BlockSize()
+	var r byte
+	switch bs {
+	case 8:
+		r = 0x1b
+	case 16:
+		r = 0x87
+	default:
+		return nil, fmt.Errorf("CMAC requires a 64-bit or 128-bit block cipher")
+	}
+	m := &CMAC{
+		block: block,
+		k1:    make([]byte, bs),
+		k2:    make([]byte, bs),
+		x:     make([]byte, bs),
+		buf:   make([]byte, bs),
+	}
+	block.Encrypt(m.k1, m.k1)
+	cmacDouble(m.k1, m.k1, r)
+	cmacDouble(m.k2, m.k1, r)
+	return m, nil
+}
+
+// cmacDouble sets dst to src·x in GF(2^n), where r is the low byte of the
+// reduction polynomial, without branching on src.
+func cmacDouble(dst, src []byte, r byte) {
+	msb := src[0] >> 7
+	for i := 0; i < len(src)-1; i++ {
+		dst[i] = src[i]<<1 | src[i+1]>>7
+	}
+	dst[len(src)-1] = src[len(src)-1]<<1 ^ byte(subtle.ConstantTimeSelect(int(msb), int(r), 0))
+}
+
+func (m *CMAC) Size() int { return m.block.BlockSize() }
+
+func (m *CMAC) BlockSize() int { return m.block.BlockSize() }
+
+func (m *CMAC) Reset() {
+	for i := range m.x {
+		m.x[i] = 0
+	}
+	m.n = 0
+}
+
+// Write processes every full block but the last, which is held back until
+// Sum because its processing depends on whether it is the end.
+func (m *CMAC) Write(p []byte) (int, error) {
+	total := len(p)
+	bs := len(m.buf)
+	for len(p) > 0 {
+		if m.n == bs {
+			xorBytes(m.x, m.x, m.buf)
+			m.block.Encrypt(m.x, m.x)
+			m.n = 0
+		}
+		c := copy(m.buf[m.n:], p)
+		m.n += c
+		p = p[c:]
+	}
+	return total, nil
+}
+
+func (m *CMAC) Sum(b []byte) []byte {
+	bs := len(m.buf)
+	last := make([]byte, bs)
+	copy(last, m.buf[:m.n])
+	k := m.k1
+	if m.n < bs {
+		last[m.n] = 0x80
+		k = m.k2
+	}
+	xorBytes(last, last, k)
+	xorBytes(last, last, m.x)
+	m.block.Encrypt(last, last)
+	return append(b, last...)
+}
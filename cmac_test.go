@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+
+	"github.com/AirWSW/go-crypto/des"
+)
+
+func tdesBlock(key []byte) cipher.Block {
+	if len(key) == 16 {
+		key = append(append([]byte(nil), key...), key[:8]...)
+	}
+	c, err := des.NewTripleDESCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// The keys are those of the SP 800-38B examples and the messages prefixes
+// of commonInput.
+var cmacTests = []struct {
+	block cipher.Block
+	n     int
+	tag   string
+}{
+	{aesBlock(commonKey128), 0, "bb1d6929e95937287fa37d129b756746"},
+	{aesBlock(commonKey128), 16, "070a16b46b4d4144f79bdd9dd04a287c"},
+	{aesBlock(commonKey128), 40, "dfa66747de9ae63030ca32611497c827"},
+	{aesBlock(commonKey128), 64, "51f0bebf7e3b9d92fc49741779363cfe"},
+	{aesBlock(commonKey256), 0, "028962f61b7bf89efc6b551f4667d983"},
+	{aesBlock(commonKey256), 16, "28a7023f452e8f82bd4bf28d8c37c35c"},
+	{aesBlock(commonKey256), 40, "aaf3d8f1de5640c232f5b169b9c911e6"},
+	{aesBlock(commonKey256), 64, "e1992190549f6ed5696a2c056c315410"},
+	{tdesBlock(hexBytes("8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5")), 0, "b7a688e122ffaf95"},
+	{tdesBlock(hexBytes("8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5")), 16, "286d394673448197"},
+	{tdesBlock(hexBytes("8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5")), 20, "743ddbe0ce2dc2ed"},
+	{tdesBlock(hexBytes("8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5")), 32, "33e6b1092400eae5"},
+	{tdesBlock(hexBytes("4cf15134a2850dd58a3d10ba80570d38")), 0, "bd2ebf9a3ba00361"},
+	{tdesBlock(hexBytes("4cf15134a2850dd58a3d10ba80570d38")), 16, "743da9f41b91ec83"},
+	{tdesBlock(hexBytes("4cf15134a2850dd58a3d10ba80570d38")), 20, "62dd1b471902bd4e"},
+	{tdesBlock(hexBytes("4cf15134a2850dd58a3d10ba80570d38")), 32, "31b1e431dabc4eb8"},
+}
+
+func hexBytes(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func Test_CMAC_Sum(t *testing.T) {
+	for i, tt := range cmacTests {
+		want := hexBytes(tt.tag)
+		m, err := NewCMAC(tt.block)
+		if err != nil {
+			t.Fatalf("#%d: NewCMAC() = %s", i, err)
+		}
+		msg := commonInput[:tt.n]
+		m.Write(msg)
+		if got := m.Sum(nil); !bytes.Equal(got, want) {
+			t.Errorf("#%d: Sum() = %x, want %x", i, got, want)
+		}
+
+		// Sum does not change the state, and writes may be split anywhere.
+		m.Reset()
+		for j := range msg {
+			m.Write(msg[j : j+1])
+			m.Sum(nil)
+		}
+		if got := m.Sum(nil); !bytes.Equal(got, want) {
+			t.Errorf("#%d: Sum() after byte writes = %x, want %x", i, got, want)
+		}
+	}
+}
+
+func Test_CMAC_Invalid(t *testing.T) {
+	if _, err := NewCMAC(noopBlock(4)); err == nil {
+		t.Errorf("NewCMAC() accepted a 32-bit block")
+	}
+	if _, err := NewCMAC(aesBlock(commonKey128)); err != nil {
+		t.Errorf("NewCMAC() = %s", err)
+	}
+}
@@ -2,9 +2,13 @@ package main
 
 import (
 	"bytes"
+	stdaes "crypto/aes"
+	"crypto/cipher"
+	stddes "crypto/des"
 	"testing"
 
 	"github.com/AirWSW/go-crypto/aes"
+	"github.com/AirWSW/go-crypto/des"
 )
 
 type noopBlock int
@@ -151,3 +155,57 @@ func Test_ctr_XORKeyStream(t *testing.T) {
 		}
 	}
 }
+
+// FuzzCTR compares NewCTR over this module's ciphers with cipher.NewCTR over
+// crypto/aes and crypto/des, writing src in pieces of step bytes to exercise
+// the buffered keystream. A 24-byte key selects 3DES if tdes is set and
+// AES-192 otherwise. The seed corpus is in testdata/fuzz/FuzzCTR.
+func FuzzCTR(f *testing.F) {
+	for _, tt := range ctrAESTests {
+		f.Add(tt.key, tt.iv, tt.in, uint8(0), false)
+	}
+	f.Add(commonKey192, commonCounter[:8], commonInput, uint8(5), true)
+	f.Fuzz(func(t *testing.T, key, iv, src []byte, step uint8, tdes bool) {
+		var c, want cipher.Block
+		var err, wantErr error
+		switch {
+		case len(key) == 8:
+			c, err = des.NewCipher(key)
+			want, wantErr = stddes.NewCipher(key)
+		case len(key) == 24 && tdes:
+			c, err = des.NewTripleDESCipher(key)
+			want, wantErr = stddes.NewTripleDESCipher(key)
+		default:
+			c, err = aes.NewCipher(key)
+			want, wantErr = stdaes.NewCipher(key)
+		}
+		if (err == nil) != (wantErr == nil) {
+			t.Fatalf("key %x: NewCipher() = %v, standard library = %v", key, err, wantErr)
+		}
+		if err != nil || len(iv) != want.BlockSize() {
+			return
+		}
+		if len(src) > 4096 {
+			src = src[:4096]
+		}
+		n := int(step)
+		if n == 0 {
+			n = len(src)
+		}
+
+		got := make([]byte, len(src))
+		ctr := NewCTR(c, iv)
+		for i := 0; i < len(src); i += n {
+			end := i + n
+			if end > len(src) {
+				end = len(src)
+			}
+			ctr.XORKeyStream(got[i:end], src[i:end])
+		}
+		exp := make([]byte, len(src))
+		cipher.NewCTR(want, iv).XORKeyStream(exp, src)
+		if !bytes.Equal(got, exp) {
+			t.Fatalf("key %x, iv %x, step %d: XORKeyStream(%x)\nhave %x\nwant %x", key, iv, n, src, got, exp)
+		}
+	})
+}
@@ -0,0 +1,266 @@
+// NIST SP 800-90A Rev. 1: Recommendation for Random Number Generation Using Deterministic Random Bit Generators, Section 10.2
+// https://csrc.nist.gov/publications/detail/sp/800-90a/rev-1/final
+
+package main
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/AirWSW/go-crypto/aes"
+)
+
+const (
+	// CTRDRBGReseedInterval is the number of Generate calls after which a
+	// CTRDRBG must be reseeded.
+	CTRDRBGReseedInterval = 1 << 48
+
+	// CTRDRBGMaxRequest is the largest number of bytes a single Generate
+	// call returns.
+	CTRDRBGMaxRequest = 1 << 16
+)
+
+// ErrReseedRequired is returned by Generate once CTRDRBGReseedInterval
+// requests have been made since the last seeding.
+var ErrReseedRequired = errors.New("ctr_drbg: reseed required")
+
+// CTRDRBG is the CTR_DRBG of SP 800-90A with AES, with or without the
+// derivation function. It is deterministic: the same entropy input, nonce,
+// personalization string and sequence of calls always give the same
+// output.
+//
+// A CTRDRBG is not safe for concurrent use.
+type CTRDRBG struct {
+	// Entropy is where Read takes entropy input from when it reseeds: on
+	// every call if PredictionResistance is set, and once the reseed
+	// interval is reached. If it is nil, crypto/rand.Reader is used.
+	Entropy              io.Reader
+	PredictionResistance bool
+
+	df            bool
+	keyLen        int
+	key           []byte
+	v             [aes.BlockSize]byte
+	block         cipher.Block
+	reseedCounter uint64
+}
+
+// NewCTRDRBG instantiates a CTR_DRBG with AES-128, AES-192 or AES-256, for
+// a keySize of 16, 24 or 32 bytes.
+//
+// With the derivation function (df) the entropy input must be at least
+// keySize bytes, and the personalization string may be of any length.
+// Without it, the entropy input must be exactly keySize+16 bytes, the
+// personalization string at most as long, and the nonce empty.
+func NewCTRDRBG(keySize int, df bool, entropy, nonce, personalization []byte) (*CTRDRBG, error) {
+	switch keySize {
+	case 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("ctr_drbg: invalid key size")
+	}
+	d := &CTRDRBG{df: df, keyLen: keySize, key: make([]byte, keySize)}
+	if !df && len(nonce) != 0 {
+		return nil, fmt.Errorf("ctr_drbg: a nonce requires the derivation function")
+	}
+	seed, err := d.seedMaterial(entropy, append(append([]byte(nil), nonce...), personalization...), personalization)
+	if err != nil {
+		return nil, err
+	}
+	if d.block, err = aes.NewCipher(d.key); err != nil {
+		return nil, err
+	}
+	d.update(seed)
+	d.reseedCounter = 1
+	return d, nil
+}
+
+// seedLen is the length of the seed, the key and V together.
+func (d *CTRDRBG) seedLen() int { return d.keyLen + aes.BlockSize }
+
+// seedMaterial returns the seed for entropy and additional input. With the
+// derivation function it is df(entropy || dfInput); without, entropy XOR
+// input padded to seedLen.
+func (d *CTRDRBG) seedMaterial(entropy, dfInput, input []byte) ([]byte, error) {
+	if d.df {
+		if len(entropy) < d.keyLen {
+			return nil, fmt.Errorf("ctr_drbg: entropy input too short")
+		}
+		return d.derive(append(append([]byte(nil), entropy...), dfInput...)), nil
+	}
+	if len(entropy) != d.seedLen() {
+		return nil, fmt.Errorf("ctr_drbg: entropy input must be %d bytes", d.seedLen())
+	}
+	if len(input) > d.seedLen() {
+		return nil, fmt.Errorf("ctr_drbg: input longer than %d bytes", d.seedLen())
+	}
+	seed := append([]byte(nil), entropy...)
+	xorBytes(seed, seed, input)
+	return seed, nil
+}
+
+// update is CTR_DRBG_Update: it encrypts successive values of V into
+// seedLen bytes, XORs them with data, which is at most seedLen bytes, and
+// makes the result the new key and V.
+func (d *CTRDRBG) update(data []byte) {
+	temp := make([]byte, (d.seedLen()+aes.BlockSize-1)/aes.BlockSize*aes.BlockSize)
+	for i := 0; i < len(temp); i += aes.BlockSize {
+		d.incV()
+		d.block.Encrypt(temp[i:], d.v[:])
+	}
+	xorBytes(temp, temp, data)
+	copy(d.key, temp)
+	copy(d.v[:], temp[d.keyLen:d.seedLen()])
+	// The key has the right length, so NewCipher cannot fail.
+	d.block, _ = aes.NewCipher(d.key)
+}
+
+// incV increments V as a 128-bit big-endian counter.
+func (d *CTRDRBG) incV() {
+	hi := binary.BigEndian.Uint64(d.v[:8])
+	lo := binary.BigEndian.Uint64(d.v[8:]) + 1
+	if lo == 0 {
+		hi++
+	}
+	binary.BigEndian.PutUint64(d.v[:8], hi)
+	binary.BigEndian.PutUint64(d.v[8:], lo)
+}
+
+// derive is Block_Cipher_df, which compresses input into seedLen bytes.
+func (d *CTRDRBG) derive(input []byte) []byte {
+	s := make([]byte, 8, 8+len(input)+aes.BlockSize)
+	binary.BigEndian.PutUint32(s, uint32(len(input)))
+	binary.BigEndian.PutUint32(s[4:], uint32(d.seedLen()))
+	s = append(s, input...)
+	s = append(s, 0x80)
+	for len(s)%aes.BlockSize != 0 {
+		s = append(s, 0)
+	}
+
+	k := make([]byte, d.keyLen)
+	for i := range k {
+		k[i] = byte(i)
+	}
+	block, _ := aes.NewCipher(k)
+	temp := make([]byte, 0, d.seedLen()+aes.BlockSize)
+	var iv [aes.BlockSize]byte
+	for i := uint32(0); len(temp) < d.seedLen(); i++ {
+		binary.BigEndian.PutUint32(iv[:], i)
+		temp = append(temp, ctrDRBGBCC(block, iv[:], s)...)
+	}
+
+	block, _ = aes.NewCipher(temp[:d.keyLen])
+	x := temp[d.keyLen:d.seedLen()]
+	out := make([]byte, 0, d.seedLen()+aes.BlockSize)
+	for len(out) < d.seedLen() {
+		block.Encrypt(x, x)
+		out = append(out, x...)
+	}
+	return out[:d.seedLen()]
+}
+
+// ctrDRBGBCC is the CBC-MAC of iv || data with a zero IV.
+func ctrDRBGBCC(block cipher.Block, iv, data []byte) []byte {
+	x := make([]byte, aes.BlockSize)
+	xorBytes(x, x, iv)
+	block.Encrypt(x, x)
+	for ; len(data) > 0; data = data[aes.BlockSize:] {
+		xorBytes(x, x, data)
+		block.Encrypt(x, x)
+	}
+	return x
+}
+
+// Reseed mixes fresh entropy input and optional additional input into the
+// state, and resets the reseed counter. The lengths are as for
+// NewCTRDRBG, with the additional input in place of the personalization
+// string.
+func (d *CTRDRBG) Reseed(entropy, additionalInput []byte) error {
+	seed, err := d.seedMaterial(entropy, additionalInput, additionalInput)
+	if err != nil {
+		return err
+	}
+	d.update(seed)
+	d.reseedCounter = 1
+	return nil
+}
+
+// Generate fills out, at most CTRDRBGMaxRequest bytes, with pseudorandom
+// bytes, after mixing in the optional additional input. It returns
+// ErrReseedRequired if the DRBG must be reseeded first.
+func (d *CTRDRBG) Generate(out, additionalInput []byte) error {
+	if len(out) > CTRDRBGMaxRequest {
+		return fmt.Errorf("ctr_drbg: request longer than %d bytes", CTRDRBGMaxRequest)
+	}
+	if d.reseedCounter > CTRDRBGReseedInterval {
+		return ErrReseedRequired
+	}
+	var add []byte
+	if len(additionalInput) > 0 {
+		if d.df {
+			add = d.derive(additionalInput)
+		} else if len(additionalInput) > d.seedLen() {
+			return fmt.Errorf("ctr_drbg: input longer than %d bytes", d.seedLen())
+		} else {
+			add = additionalInput
+		}
+		d.update(add)
+	}
+	var buf [aes.BlockSize]byte
+	for i := 0; i < len(out); i += aes.BlockSize {
+		d.incV()
+		d.block.Encrypt(buf[:], d.v[:])
+		copy(out[i:], buf[:])
+	}
+	d.update(add)
+	d.reseedCounter++
+	return nil
+}
+
+// GenerateWithPredictionResistance reseeds with entropy and the additional
+// input, then fills out as Generate does, so that the output does not
+// depend on the state being secret.
+func (d *CTRDRBG) GenerateWithPredictionResistance(out, entropy, additionalInput []byte) error {
+	if err := d.Reseed(entropy, additionalInput); err != nil {
+		return err
+	}
+	return d.Generate(out, nil)
+}
+
+// Read fills p with pseudorandom bytes, in as many Generate calls as it
+// takes. It reseeds from Entropy before each call if PredictionResistance
+// is set, and whenever the reseed interval is reached.
+func (d *CTRDRBG) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		chunk := p[n:]
+		if len(chunk) > CTRDRBGMaxRequest {
+			chunk = chunk[:CTRDRBGMaxRequest]
+		}
+		if d.PredictionResistance || d.reseedCounter > CTRDRBGReseedInterval {
+			if err := d.reseedFromEntropy(); err != nil {
+				return n, err
+			}
+		}
+		if err := d.Generate(chunk, nil); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+	}
+	return n, nil
+}
+
+func (d *CTRDRBG) reseedFromEntropy() error {
+	r := d.Entropy
+	if r == nil {
+		r = rand.Reader
+	}
+	entropy := make([]byte, d.seedLen())
+	if _, err := io.ReadFull(r, entropy); err != nil {
+		return err
+	}
+	return d.Reseed(entropy, nil)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The files in testdata/drbg are the AES sections of the CTR_DRBG vectors
+// of the CAVP, cut to two vectors per group. Each instantiates the DRBG,
+// calls Generate twice and gives the output of the second call, after a
+// reseed in the pr_false file and with prediction resistance in pr_true.
+func Test_CTRDRBG_CAVP(t *testing.T) {
+	for _, file := range []string{"CTR_DRBG_no_reseed.rsp", "CTR_DRBG_pr_false.rsp", "CTR_DRBG_pr_true.rsp"} {
+		f, err := os.Open(filepath.Join("testdata", "drbg", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := parseCAVP(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %s", file, err)
+		}
+		for _, r := range records {
+			var keySize int
+			switch {
+			case strings.HasPrefix(r.section, "AES-128 "):
+				keySize = 16
+			case strings.HasPrefix(r.section, "AES-192 "):
+				keySize = 24
+			case strings.HasPrefix(r.section, "AES-256 "):
+				keySize = 32
+			default:
+				t.Fatalf("%s:%d: unknown section [%s]", file, r.line, r.section)
+			}
+			df := strings.HasSuffix(r.section, " use df")
+			field := func(name string, i int) []byte {
+				b, err := cavpField(cavpRecord{fields: map[string]string{name: r.values[name][i]}}, name)
+				if err != nil {
+					t.Fatalf("%s:%d: %s", file, r.line, err)
+				}
+				return b
+			}
+
+			d, err := NewCTRDRBG(keySize, df, field("EntropyInput", 0), field("Nonce", 0), field("PersonalizationString", 0))
+			if err != nil {
+				t.Fatalf("%s:%d: NewCTRDRBG() = %s", file, r.line, err)
+			}
+			want := field("ReturnedBits", 0)
+			got := make([]byte, len(want))
+			if _, ok := r.fields["EntropyInputReseed"]; ok {
+				if err := d.Reseed(field("EntropyInputReseed", 0), field("AdditionalInputReseed", 0)); err != nil {
+					t.Fatalf("%s:%d: Reseed() = %s", file, r.line, err)
+				}
+			}
+			for i := 0; i < 2; i++ {
+				if r.params["PredictionResistance"] == "True" {
+					err = d.GenerateWithPredictionResistance(got, field("EntropyInputPR", i), field("AdditionalInput", i))
+				} else {
+					err = d.Generate(got, field("AdditionalInput", i))
+				}
+				if err != nil {
+					t.Fatalf("%s:%d: Generate() = %s", file, r.line, err)
+				}
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s:%d: ReturnedBits = %x, want %x", file, r.line, got, want)
+			}
+		}
+	}
+}
+
+// Test_CTRDRBG_Read checks that Read returns what Generate does, in chunks
+// of at most CTRDRBGMaxRequest bytes, and reseeds for prediction
+// resistance.
+func Test_CTRDRBG_Read(t *testing.T) {
+	entropy := commonInput[:32]
+	d1, _ := NewCTRDRBG(16, false, entropy, nil, nil)
+	d2, _ := NewCTRDRBG(16, false, entropy, nil, nil)
+	got := make([]byte, CTRDRBGMaxRequest+20)
+	if n, err := d1.Read(got); n != len(got) || err != nil {
+		t.Fatalf("Read() = %d, %s", n, err)
+	}
+	want := make([]byte, len(got))
+	d2.Generate(want[:CTRDRBGMaxRequest], nil)
+	d2.Generate(want[CTRDRBGMaxRequest:], nil)
+	if !bytes.Equal(got, want) {
+		t.Errorf("Read() differs from Generate()")
+	}
+
+	d1, _ = NewCTRDRBG(32, true, entropy, commonCounter, []byte("personalization"))
+	d2, _ = NewCTRDRBG(32, true, entropy, commonCounter, []byte("personalization"))
+	d1.PredictionResistance = true
+	d1.Entropy = bytes.NewReader(commonInput)
+	got = make([]byte, 40)
+	d1.Read(got)
+	d2.GenerateWithPredictionResistance(want[:40], commonInput[:48], nil)
+	if !bytes.Equal(got, want[:40]) {
+		t.Errorf("Read() with prediction resistance = %x, want %x", got, want[:40])
+	}
+	if _, err := d1.Read(got); err == nil {
+		t.Errorf("Read() succeeded without entropy")
+	}
+
+	d2.reseedCounter = CTRDRBGReseedInterval + 1
+	if err := d2.Generate(got, nil); err != ErrReseedRequired {
+		t.Errorf("Generate() past the reseed interval = %v, want ErrReseedRequired", err)
+	}
+}
+
+func Test_CTRDRBG_Invalid(t *testing.T) {
+	entropy := commonInput[:48]
+	for i, tt := range []struct {
+		keySize                  int
+		df                       bool
+		entropy, nonce, personal []byte
+	}{
+		{20, true, entropy, nil, nil},
+		{16, true, entropy[:15], nil, nil},
+		{16, false, entropy, nil, nil},
+		{16, false, entropy[:32], commonCounter, nil},
+		{16, false, entropy[:32], nil, entropy[:33]},
+	} {
+		if _, err := NewCTRDRBG(tt.keySize, tt.df, tt.entropy, tt.nonce, tt.personal); err == nil {
+			t.Errorf("#%d: NewCTRDRBG() succeeded", i)
+		}
+	}
+	d, _ := NewCTRDRBG(16, false, entropy[:32], nil, nil)
+	if err := d.Generate(make([]byte, CTRDRBGMaxRequest+1), nil); err == nil {
+		t.Errorf("Generate() of %d bytes succeeded", CTRDRBGMaxRequest+1)
+	}
+	if err := d.Generate(make([]byte, 16), entropy[:33]); err == nil {
+		t.Errorf("Generate() with 33 bytes of additional input succeeded")
+	}
+	if err := d.Reseed(entropy[:31], nil); err == nil {
+		t.Errorf("Reseed() with 31 bytes of entropy succeeded")
+	}
+}
@@ -0,0 +1,180 @@
+// Addendum to NIST SP 800-38A: Three Variants of Ciphertext Stealing for CBC Mode
+// https://csrc.nist.gov/publications/detail/sp/800-38a/addendum/final
+
+package main
+
+import (
+	"crypto/cipher"
+)
+
+// Ciphertext stealing variants of CBC mode. CS1 keeps the ciphertext blocks
+// in order, CS2 swaps the last two blocks only when the final block is
+// partial, and CS3 (the Kerberos variant of RFC 3962) always swaps them.
+const (
+	CS1 = iota + 1
+	CS2
+	CS3
+)
+
+type cbcCS struct {
+	block   cipher.Block
+	iv      []byte
+	variant int
+}
+
+func newCBCCS(block cipher.Block, iv []byte, variant int) *cbcCS {
+	if len(iv) != block.BlockSize() {
+		panic("invalid IV length")
+	}
+	if variant < CS1 || variant > CS3 {
+		panic("invalid ciphertext stealing variant")
+	}
+	b := make([]byte, len(iv))
+	copy(b, iv)
+	return &cbcCS{
+		block:   block,
+		iv:      b,
+		variant: variant,
+	}
+}
+
+// ctsSwapped reports whether the last two ciphertext blocks are stored in
+// reverse order for a final block of d out of bs bytes.
+func ctsSwapped(variant, d, bs int) bool {
+	return variant == CS3 || (variant == CS2 && d != bs)
+}
+
+type cbcCSEncrypter cbcCS
+
+// NewCBCCSEncrypter returns a BlockMode which encrypts in CBC mode with
+// ciphertext stealing, using the given Block and variant (CS1, CS2 or CS3).
+// The length of iv must be the same as the Block's block size.
+//
+// Each call to CryptBlocks encrypts one complete message, which must be at
+// least one block long but need not be a multiple of the block size. The
+// ciphertext has the same length as the plaintext.
+func NewCBCCSEncrypter(block cipher.Block, iv []byte, variant int) cipher.BlockMode {
+	return (*cbcCSEncrypter)(newCBCCS(block, iv, variant))
+}
+
+func (x *cbcCSEncrypter) BlockSize() int { return x.block.BlockSize() }
+
+func (x *cbcCSEncrypter) CryptBlocks(dst, src []byte) {
+	bs := x.block.BlockSize()
+	if len(src) < bs {
+		panic("input smaller than one block")
+	}
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+	prev := make([]byte, bs)
+	copy(prev, x.iv)
+	if len(src) == bs {
+		xorBytes(dst, src, prev)
+		x.block.Encrypt(dst, dst)
+		return
+	}
+
+	d := len(src) % bs
+	if d == 0 {
+		d = bs
+	}
+	head := len(src) - d - bs
+
+	// Encrypt the plaintext up to and including block n-1 in plain CBC mode.
+	for i := 0; i <= head; i += bs {
+		xorBytes(prev, src[i:i+bs], prev)
+		x.block.Encrypt(prev, prev)
+		copy(dst[i:], prev)
+	}
+
+	// The final partial block is padded with zeros, which is what lets the
+	// decrypter recover the bytes of block n-1 that are not transmitted.
+	last := make([]byte, bs)
+	copy(last, src[head+bs:])
+	xorBytes(last, last, prev)
+	x.block.Encrypt(last, last)
+
+	if ctsSwapped(x.variant, d, bs) {
+		copy(dst[head:], last)
+		copy(dst[head+bs:], prev[:d])
+	} else {
+		copy(dst[head:], prev[:d])
+		copy(dst[head+d:], last)
+	}
+}
+
+type cbcCSDecrypter cbcCS
+
+// NewCBCCSDecrypter returns a BlockMode which decrypts in CBC mode with
+// ciphertext stealing, using the given Block and variant (CS1, CS2 or CS3).
+// The length of iv must be the same as the Block's block size and must match
+// the iv used to encrypt the data.
+//
+// Each call to CryptBlocks decrypts one complete message, which must be at
+// least one block long.
+func NewCBCCSDecrypter(block cipher.Block, iv []byte, variant int) cipher.BlockMode {
+	return (*cbcCSDecrypter)(newCBCCS(block, iv, variant))
+}
+
+func (x *cbcCSDecrypter) BlockSize() int { return x.block.BlockSize() }
+
+func (x *cbcCSDecrypter) CryptBlocks(dst, src []byte) {
+	bs := x.block.BlockSize()
+	if len(src) < bs {
+		panic("input smaller than one block")
+	}
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+	if len(src) == bs {
+		x.block.Decrypt(dst, src)
+		xorBytes(dst, dst, x.iv)
+		return
+	}
+
+	d := len(src) % bs
+	if d == 0 {
+		d = bs
+	}
+	head := len(src) - d - bs
+
+	// Copy out the last two (possibly swapped) ciphertext blocks and the
+	// ciphertext block preceding them before dst may overwrite src.
+	cn1 := make([]byte, bs)
+	cn := make([]byte, bs)
+	if ctsSwapped(x.variant, d, bs) {
+		copy(cn, src[head:head+bs])
+		copy(cn1, src[head+bs:])
+	} else {
+		copy(cn1, src[head:head+d])
+		copy(cn, src[head+d:])
+	}
+	cn2 := make([]byte, bs)
+	if head == 0 {
+		copy(cn2, x.iv)
+	} else {
+		copy(cn2, src[head-bs:head])
+	}
+
+	// Decrypting block n yields the zero padded final plaintext XORed with
+	// block n-1, whose missing tail is therefore the tail of the result.
+	z := make([]byte, bs)
+	x.block.Decrypt(z, cn)
+	copy(cn1[d:], z[d:])
+	xorBytes(z, z[:d], cn1)
+	x.block.Decrypt(cn1, cn1)
+	xorBytes(cn1, cn1, cn2)
+
+	prev := make([]byte, bs)
+	copy(prev, x.iv)
+	tmp := make([]byte, bs)
+	for i := 0; i < head; i += bs {
+		copy(tmp, src[i:i+bs])
+		x.block.Decrypt(dst[i:i+bs], tmp)
+		xorBytes(dst[i:i+bs], dst[i:i+bs], prev)
+		prev, tmp = tmp, prev
+	}
+	copy(dst[head:], cn1)
+	copy(dst[head+bs:], z[:d])
+}
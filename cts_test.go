@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"testing"
+
+	"github.com/AirWSW/go-crypto/aes"
+	"github.com/AirWSW/go-crypto/des"
+)
+
+var kerberosKey = []byte{0x63, 0x68, 0x69, 0x63, 0x6b, 0x65, 0x6e, 0x20, 0x74, 0x65, 0x72, 0x69, 0x79, 0x61, 0x6b, 0x69}
+
+// "I would like the General Gau's Chicken, please, and wonton soup."
+var kerberosInput = []byte{
+	0x49, 0x20, 0x77, 0x6f, 0x75, 0x6c, 0x64, 0x20, 0x6c, 0x69, 0x6b, 0x65, 0x20, 0x74, 0x68, 0x65,
+	0x20, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x6c, 0x20, 0x47, 0x61, 0x75, 0x27, 0x73, 0x20, 0x43,
+	0x68, 0x69, 0x63, 0x6b, 0x65, 0x6e, 0x2c, 0x20, 0x70, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2c, 0x20,
+	0x61, 0x6e, 0x64, 0x20, 0x77, 0x6f, 0x6e, 0x74, 0x6f, 0x6e, 0x20, 0x73, 0x6f, 0x75, 0x70, 0x2e,
+}
+
+// RFC 3962 Appendix B. Sample Test Vectors (AES-128, CBC-CS3, zero IV)
+var ctsKerberosTests = []struct {
+	in  []byte
+	out []byte
+}{
+	{
+		kerberosInput[:17],
+		[]byte{
+			0xc6, 0x35, 0x35, 0x68, 0xf2, 0xbf, 0x8c, 0xb4, 0xd8, 0xa5, 0x80, 0x36, 0x2d, 0xa7, 0xff, 0x7f,
+			0x97,
+		},
+	},
+	{
+		kerberosInput[:31],
+		[]byte{
+			0xfc, 0x00, 0x78, 0x3e, 0x0e, 0xfd, 0xb2, 0xc1, 0xd4, 0x45, 0xd4, 0xc8, 0xef, 0xf7, 0xed, 0x22,
+			0x97, 0x68, 0x72, 0x68, 0xd6, 0xec, 0xcc, 0xc0, 0xc0, 0x7b, 0x25, 0xe2, 0x5e, 0xcf, 0xe5,
+		},
+	},
+	{
+		kerberosInput[:32],
+		[]byte{
+			0x39, 0x31, 0x25, 0x23, 0xa7, 0x86, 0x62, 0xd5, 0xbe, 0x7f, 0xcb, 0xcc, 0x98, 0xeb, 0xf5, 0xa8,
+			0x97, 0x68, 0x72, 0x68, 0xd6, 0xec, 0xcc, 0xc0, 0xc0, 0x7b, 0x25, 0xe2, 0x5e, 0xcf, 0xe5, 0x84,
+		},
+	},
+	{
+		kerberosInput[:47],
+		[]byte{
+			0x97, 0x68, 0x72, 0x68, 0xd6, 0xec, 0xcc, 0xc0, 0xc0, 0x7b, 0x25, 0xe2, 0x5e, 0xcf, 0xe5, 0x84,
+			0xb3, 0xff, 0xfd, 0x94, 0x0c, 0x16, 0xa1, 0x8c, 0x1b, 0x55, 0x49, 0xd2, 0xf8, 0x38, 0x02, 0x9e,
+			0x39, 0x31, 0x25, 0x23, 0xa7, 0x86, 0x62, 0xd5, 0xbe, 0x7f, 0xcb, 0xcc, 0x98, 0xeb, 0xf5,
+		},
+	},
+	{
+		kerberosInput[:48],
+		[]byte{
+			0x97, 0x68, 0x72, 0x68, 0xd6, 0xec, 0xcc, 0xc0, 0xc0, 0x7b, 0x25, 0xe2, 0x5e, 0xcf, 0xe5, 0x84,
+			0x9d, 0xad, 0x8b, 0xbb, 0x96, 0xc4, 0xcd, 0xc0, 0x3b, 0xc1, 0x03, 0xe1, 0xa1, 0x94, 0xbb, 0xd8,
+			0x39, 0x31, 0x25, 0x23, 0xa7, 0x86, 0x62, 0xd5, 0xbe, 0x7f, 0xcb, 0xcc, 0x98, 0xeb, 0xf5, 0xa8,
+		},
+	},
+	{
+		kerberosInput[:64],
+		[]byte{
+			0x97, 0x68, 0x72, 0x68, 0xd6, 0xec, 0xcc, 0xc0, 0xc0, 0x7b, 0x25, 0xe2, 0x5e, 0xcf, 0xe5, 0x84,
+			0x39, 0x31, 0x25, 0x23, 0xa7, 0x86, 0x62, 0xd5, 0xbe, 0x7f, 0xcb, 0xcc, 0x98, 0xeb, 0xf5, 0xa8,
+			0x48, 0x07, 0xef, 0xe8, 0x36, 0xee, 0x89, 0xa5, 0x26, 0x73, 0x0d, 0xbc, 0x2f, 0x7b, 0xc8, 0x40,
+			0x9d, 0xad, 0x8b, 0xbb, 0x96, 0xc4, 0xcd, 0xc0, 0x3b, 0xc1, 0x03, 0xe1, 0xa1, 0x94, 0xbb, 0xd8,
+		},
+	},
+}
+
+func Test_cbcCS_Kerberos(t *testing.T) {
+	c, _ := aes.NewCipher(kerberosKey)
+	iv := make([]byte, aes.BlockSize)
+	for i, tt := range ctsKerberosTests {
+		got := make([]byte, len(tt.in))
+		NewCBCCSEncrypter(c, iv, CS3).CryptBlocks(got, tt.in)
+		if !bytes.Equal(got, tt.out) {
+			t.Errorf("#%d: encrypt\nhave %x\nwant %x", i, got, tt.out)
+		}
+		NewCBCCSDecrypter(c, iv, CS3).CryptBlocks(got, tt.out)
+		if !bytes.Equal(got, tt.in) {
+			t.Errorf("#%d: decrypt\nhave %x\nwant %x", i, got, tt.in)
+		}
+	}
+}
+
+// Test_cbcCS_Variants checks every variant against plain CBC mode, from which
+// the SP 800-38A addendum defines them, for each length up to four blocks.
+func Test_cbcCS_Variants(t *testing.T) {
+	des3, _ := des.NewTripleDESCipher(tripleDESKey)
+	blocks := []cipher.Block{aesBlock(commonKey128), aesBlock(commonKey256), des3}
+	for _, b := range blocks {
+		bs := b.BlockSize()
+		iv := commonCounter[:bs]
+		for n := bs; n <= 4*bs; n++ {
+			in := commonInput[:n]
+			d := n % bs
+			if d == 0 {
+				d = bs
+			}
+
+			padded := make([]byte, (n+bs-1)/bs*bs)
+			copy(padded, in)
+			cbc := make([]byte, len(padded))
+			cipher.NewCBCEncrypter(b, iv).CryptBlocks(cbc, padded)
+			head := len(cbc) - 2*bs
+			if n == bs {
+				head = -bs
+			}
+
+			for _, variant := range []int{CS1, CS2, CS3} {
+				want := append([]byte{}, cbc...)
+				if n > bs {
+					cn1, cn := cbc[head:head+bs], cbc[head+bs:]
+					if variant == CS3 || (variant == CS2 && d != bs) {
+						want = append(append(want[:head], cn...), cn1[:d]...)
+					} else {
+						want = append(append(want[:head], cn1[:d]...), cn...)
+					}
+				}
+
+				got := make([]byte, n)
+				NewCBCCSEncrypter(b, iv, variant).CryptBlocks(got, in)
+				if !bytes.Equal(got, want) {
+					t.Errorf("CS%d/%d/%d: encrypt\nhave %x\nwant %x", variant, bs, n, got, want)
+				}
+
+				// Decrypt in place.
+				NewCBCCSDecrypter(b, iv, variant).CryptBlocks(got, got)
+				if !bytes.Equal(got, in) {
+					t.Errorf("CS%d/%d/%d: decrypt\nhave %x\nwant %x", variant, bs, n, got, in)
+				}
+			}
+		}
+	}
+}
+
+var tripleDESKey = []byte{
+	0xcb, 0x10, 0x7d, 0xda, 0x7e, 0x96, 0x57, 0x0a,
+	0xe8, 0xeb, 0xe8, 0x07, 0x8e, 0x87, 0xd3, 0x57,
+	0xb2, 0x61, 0x12, 0xb8, 0x2a, 0x90, 0xb7, 0x2f,
+}
+
+func aesBlock(key []byte) cipher.Block {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
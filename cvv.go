@@ -0,0 +1,118 @@
+// Visa Payment Technology Standards Manual: Card Verification Value and PIN Verification Value
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/AirWSW/go-crypto/des"
+)
+
+// The service codes from which CVV computes the CVV2 printed on a card and
+// the iCVV of its chip, instead of the CVV or CVC of the magnetic stripe.
+const (
+	CVV2ServiceCode = "000"
+	ICVVServiceCode = "999"
+)
+
+// CVV returns the 3-digit card verification value of a card under the
+// 16-byte key CVK A || CVK B. expiry is YYMM and serviceCode 3 digits.
+// PAN || expiry || service code is padded with zeros to two blocks; the
+// first is encrypted under CVK A with DES, XORed with the second and
+// encrypted under both keys with 3DES.
+//
+// If dectab is empty, the result is decimalized as Visa specifies: its
+// decimal digits, then its other nibbles minus 10. Otherwise it is a
+// decimalization table of 16 digits, to which each nibble is an index.
+func CVV(cvk []byte, pan, expiry, serviceCode, dectab string) (string, error) {
+	if len(cvk) != 16 {
+		return "", fmt.Errorf("invalid key size")
+	}
+	if len(pan) < 1 || len(pan) > 19 || !isDigits(pan) {
+		return "", fmt.Errorf("invalid PAN")
+	}
+	if len(expiry) != 4 || !isDigits(expiry) || len(serviceCode) != 3 || !isDigits(serviceCode) {
+		return "", fmt.Errorf("invalid expiry date or service code")
+	}
+	if err := checkDecimalizationTable(dectab); err != nil {
+		return "", err
+	}
+	block1, err := des.NewCipher(cvk[:8])
+	if err != nil {
+		return "", err
+	}
+	block2, err := newTwoKeyTDES(cvk)
+	if err != nil {
+		return "", err
+	}
+
+	data := pan + expiry + serviceCode
+	b, _ := hex.DecodeString(data + strings.Repeat("0", 32-len(data)))
+	block1.Encrypt(b[:8], b[:8])
+	xorBytes(b[:8], b[:8], b[8:])
+	block2.Encrypt(b[:8], b[:8])
+	return decimalize(b[:8], 3, dectab), nil
+}
+
+// PVV returns the 4-digit Visa PIN verification value of pin, from 4 to 12
+// digits, under the 16-byte PIN verification key. pvki is the key index,
+// from 0 to 9. The 11 rightmost digits of the PAN without the check digit,
+// pvki and the first 4 digits of the PIN are encrypted with 3DES and
+// decimalized as in CVV.
+func PVV(pvk []byte, pan string, pvki int, pin, dectab string) (string, error) {
+	if len(pvk) != 16 {
+		return "", fmt.Errorf("invalid key size")
+	}
+	if len(pan) < 12 || len(pan) > 19 || !isDigits(pan) {
+		return "", fmt.Errorf("invalid PAN")
+	}
+	if pvki < 0 || pvki > 9 {
+		return "", fmt.Errorf("PVKI must be a digit")
+	}
+	if len(pin) < 4 || len(pin) > 12 || !isDigits(pin) {
+		return "", fmt.Errorf("PIN must be 4 to 12 digits")
+	}
+	if err := checkDecimalizationTable(dectab); err != nil {
+		return "", err
+	}
+	block, err := newTwoKeyTDES(pvk)
+	if err != nil {
+		return "", err
+	}
+
+	tsp, _ := hex.DecodeString(pan[len(pan)-12:len(pan)-1] + strconv.Itoa(pvki) + pin[:4])
+	block.Encrypt(tsp, tsp)
+	return decimalize(tsp, 4, dectab), nil
+}
+
+func checkDecimalizationTable(dectab string) error {
+	if dectab != "" && (len(dectab) != 16 || !isDigits(dectab)) {
+		return fmt.Errorf("decimalization table must be 16 digits")
+	}
+	return nil
+}
+
+// decimalize returns the first n digits of the nibbles of b, mapped through
+// dectab or, if it is empty, in the two passes of CVV.
+func decimalize(b []byte, n int, dectab string) string {
+	digits := make([]byte, 0, 2*len(b))
+	if dectab != "" {
+		for _, c := range b {
+			digits = append(digits, dectab[c>>4], dectab[c&0xf])
+		}
+		return string(digits[:n])
+	}
+	for _, decimal := range []bool{true, false} {
+		for _, c := range b {
+			for _, d := range []byte{c >> 4, c & 0xf} {
+				if d < 10 == decimal {
+					digits = append(digits, '0'+d%10)
+				}
+			}
+		}
+	}
+	return string(digits[:n])
+}
@@ -0,0 +1,101 @@
+package main
+
+import "testing"
+
+var cvvKey = hexBytes("0123456789abcdeffedcba9876543210")
+
+// The first vector is the widely reproduced example of the Visa CVV method.
+// No published PVV or decimalization table examples were available. All the
+// CVVs and PVVs are recomputed with openssl by testdata/payments/cvv.sh.
+var cvvTests = []struct {
+	pan, expiry, serviceCode string
+	dectab                   string
+	cvv                      string
+}{
+	{"4123456789012345", "8701", "101", "", "561"},
+	{"4123456789012345", "8701", CVV2ServiceCode, "", "636"},
+	{"4123456789012345", "8701", ICVVServiceCode, "", "651"},
+	{"5123456789012346", "2512", "201", "", "953"},
+	{"4999988887777", "9912", "101", "", "899"},
+	{"5413330089020011", "2612", CVV2ServiceCode, "", "067"},
+	{"4123456789012345", "8701", CVV2ServiceCode, "0123456789012345", "623"},
+	{"4999988887777000", "9912", "101", "0123456789012345", "428"},
+	{"5413330089020011", "2612", CVV2ServiceCode, "0123456789012345", "412"},
+}
+
+func Test_CVV(t *testing.T) {
+	for i, tt := range cvvTests {
+		got, err := CVV(cvvKey, tt.pan, tt.expiry, tt.serviceCode, tt.dectab)
+		if err != nil || got != tt.cvv {
+			t.Errorf("#%d: CVV() = %q, %v, want %q", i, got, err, tt.cvv)
+		}
+	}
+}
+
+var pvvTests = []struct {
+	pan    string
+	pvki   int
+	pin    string
+	dectab string
+	pvv    string
+}{
+	{"4123456789012345", 1, "1234", "", "1894"},
+	// Only the first 4 digits of the PIN count.
+	{"4123456789012345", 1, "12345678", "", "1894"},
+	{"4003000123456781", 1, "1234", "", "6384"},
+	{"5413330089020011", 6, "9876", "", "5649"},
+	{"4999988887777000", 0, "0000", "", "0309"},
+	// Encrypts to fccfc6d56aeedbcf, with three decimal digits.
+	{"4123456789012345", 1, "1216", "", "6565"},
+	{"4123456789012345", 1, "1234", "9876543210987654", "8105"},
+	{"5413330089020011", 6, "9876", "9876543210987654", "7437"},
+}
+
+func Test_PVV(t *testing.T) {
+	for i, tt := range pvvTests {
+		got, err := PVV(cvvKey, tt.pan, tt.pvki, tt.pin, tt.dectab)
+		if err != nil || got != tt.pvv {
+			t.Errorf("#%d: PVV() = %q, %v, want %q", i, got, err, tt.pvv)
+		}
+	}
+}
+
+func Test_CVV_Invalid(t *testing.T) {
+	for i, tt := range []struct {
+		key                      []byte
+		pan, expiry, serviceCode string
+		dectab                   string
+	}{
+		{cvvKey[:8], "4123456789012345", "8701", "101", ""},
+		{cvvKey, "", "8701", "101", ""},
+		{cvvKey, "41234567890123456789", "8701", "101", ""},
+		{cvvKey, "4123 4567 8901 2345", "8701", "101", ""},
+		{cvvKey, "4123456789012345", "870", "101", ""},
+		{cvvKey, "4123456789012345", "8701", "1O1", ""},
+		{cvvKey, "4123456789012345", "8701", "101", "012345678901234"},
+		{cvvKey, "4123456789012345", "8701", "101", "0123456789abcdef"},
+	} {
+		if _, err := CVV(tt.key, tt.pan, tt.expiry, tt.serviceCode, tt.dectab); err == nil {
+			t.Errorf("#%d: CVV() succeeded", i)
+		}
+	}
+	for i, tt := range []struct {
+		key    []byte
+		pan    string
+		pvki   int
+		pin    string
+		dectab string
+	}{
+		{append(cvvKey[:16:16], cvvKey[:8]...), "4123456789012345", 1, "1234", ""},
+		{cvvKey, "41234567890", 1, "1234", ""},
+		{cvvKey, "4123456789012345", 10, "1234", ""},
+		{cvvKey, "4123456789012345", -1, "1234", ""},
+		{cvvKey, "4123456789012345", 1, "123", ""},
+		{cvvKey, "4123456789012345", 1, "12a4", ""},
+		{cvvKey, "4123456789012345", 1, "1234", "98765432109876"},
+	} {
+		if _, err := PVV(tt.key, tt.pan, tt.pvki, tt.pin, tt.dectab); err == nil {
+			t.Errorf("#%d: PVV() succeeded", i)
+		}
+	}
+}
@@ -45,7 +45,7 @@ func (c *desCipher) Decrypt(dst, src []byte) {
 }
 
 func encryptBlock(subKeys []uint64, b uint64) uint64 {
-	return cryptBlock(subKeys, b)
+	return cryptBlock(subKeys, b, expansionFunction[:], 0)
 }
 
 func decryptBlock(subKeys []uint64, b uint64) uint64 {
@@ -53,7 +53,7 @@ func decryptBlock(subKeys []uint64, b uint64) uint64 {
 	for i := len(subKeys) - 1; i >= 0; i-- {
 		tk = append(tk, subKeys[i])
 	}
-	return cryptBlock(tk, b)
+	return cryptBlock(tk, b, expansionFunction[:], 0)
 }
 
 var initialPermutation = [64]uint8{
@@ -78,11 +78,15 @@ var finalPermutation = [64]uint8{
 	33, 1, 41, 9, 49, 17, 57, 25,
 }
 
-func cryptBlock(subKeys []uint64, b uint64) uint64 {
+// cryptBlock runs one Feistel round on b for each of subKeys, with the E
+// table expansion. Each bit of the 24-bit saltMask that is set swaps a bit
+// of the left half of the expansion in feistel with the bit 24 places to
+// its right, as crypt(3) does.
+func cryptBlock(subKeys []uint64, b uint64, expansion []uint8, saltMask uint32) uint64 {
 	b = permute(b, initialPermutation[:], 64)
 	l, r := uint32(b>>32), uint32(b)
 	for _, k := range subKeys {
-		l, r = feistel(l, r, k)
+		l, r = feistel(l, r, k, expansion, saltMask)
 	}
 	b = (uint64(r) << 32) | uint64(l)
 	b = permute(b, finalPermutation[:], 64)
@@ -166,8 +170,10 @@ var permutationFunction = [32]uint8{
 	19, 13, 30, 6, 22, 11, 4, 25,
 }
 
-func feistel(l, r uint32, k uint64) (uint32, uint32) {
-	k ^= permute(uint64(r), expansionFunction[:], 32)
+func feistel(l, r uint32, k uint64, expansion []uint8, saltMask uint32) (uint32, uint32) {
+	e := permute(uint64(r), expansion, 32)
+	f := (e>>24 ^ e) & uint64(saltMask)
+	k ^= e ^ (f<<24 | f)
 	s := uint32(0)
 	for i, sbox := range sBoxes {
 		i = 7 - i
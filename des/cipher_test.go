@@ -2,6 +2,8 @@ package des
 
 import (
 	"bytes"
+	"crypto/cipher"
+	stddes "crypto/des"
 	"testing"
 )
 
@@ -233,3 +235,54 @@ func Test_tripleDESCipher_Decrypt(t *testing.T) {
 		}
 	}
 }
+
+// FuzzCipher compares NewCipher with crypto/des on random keys and blocks.
+// The seed corpus is in testdata/fuzz/FuzzCipher.
+func FuzzCipher(f *testing.F) {
+	for _, tt := range desCipherTests {
+		f.Add(tt.key, tt.dec)
+	}
+	f.Fuzz(func(t *testing.T, key, src []byte) {
+		c, err := NewCipher(key)
+		want, wantErr := stddes.NewCipher(key)
+		compareBlocks(t, key, c, err, want, wantErr, src)
+	})
+}
+
+// FuzzTripleDESCipher compares NewTripleDESCipher with crypto/des on random
+// keys and blocks. The seed corpus is in testdata/fuzz/FuzzTripleDESCipher.
+func FuzzTripleDESCipher(f *testing.F) {
+	for _, tt := range tripleDESCipherTests {
+		f.Add(tt.key, tt.dec)
+	}
+	f.Fuzz(func(t *testing.T, key, src []byte) {
+		c, err := NewTripleDESCipher(key)
+		want, wantErr := stddes.NewTripleDESCipher(key)
+		compareBlocks(t, key, c, err, want, wantErr, src)
+	})
+}
+
+// compareBlocks checks that c and want were both created or both rejected,
+// and that they encrypt and decrypt each whole block of src alike.
+func compareBlocks(t *testing.T, key []byte, c cipher.Block, err error, want cipher.Block, wantErr error, src []byte) {
+	if (err != nil) != (wantErr != nil) {
+		t.Fatalf("key %x: error = %v, crypto/des error = %v", key, err, wantErr)
+	}
+	if err != nil {
+		return
+	}
+	for len(src) >= BlockSize {
+		got, exp := make([]byte, BlockSize), make([]byte, BlockSize)
+		c.Encrypt(got, src)
+		want.Encrypt(exp, src)
+		if !bytes.Equal(got, exp) {
+			t.Fatalf("key %x: Encrypt(%x) = %x, crypto/des = %x", key, src[:BlockSize], got, exp)
+		}
+		c.Decrypt(got, src)
+		want.Decrypt(exp, src)
+		if !bytes.Equal(got, exp) {
+			t.Fatalf("key %x: Decrypt(%x) = %x, crypto/des = %x", key, src[:BlockSize], got, exp)
+		}
+		src = src[BlockSize:]
+	}
+}
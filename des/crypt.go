@@ -0,0 +1,148 @@
+// The Open Group Base Specifications Issue 7: crypt
+// https://pubs.opengroup.org/onlinepubs/9699919799/functions/crypt.html
+
+// FreeSec: libcrypt for NetBSD
+// https://github.com/freebsd/freebsd-src/blob/main/secure/lib/libcrypt/crypt-des.c
+
+package des
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// cryptAlphabet encodes the salt, count and hash of crypt(3) six bits to a
+// character.
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// Crypt returns the crypt(3) hash of password with the salt and parameters
+// in setting, which may be a previous hash:
+//
+//   - Traditional DES: two salt characters. The first 8 characters of the
+//     password are the key, and a zero block is encrypted 25 times with
+//     the 12-bit salt perturbing the expansion.
+//   - BSDi extended DES: "_" followed by four characters of iteration count
+//     and four of 24-bit salt. Every character of the password counts.
+//
+// The salt and count characters are from "./0-9A-Za-z", the least
+// significant first. A count of zero is rejected.
+func Crypt(password, setting string) (string, error) {
+	var (
+		key    [8]byte
+		count  uint32 = 25
+		salt   uint32
+		prefix string
+	)
+	for i := 0; i < len(key) && i < len(password); i++ {
+		key[i] = password[i] << 1
+	}
+	subKeys := newSubKeys(binary.BigEndian.Uint64(key[:]))
+
+	if strings.HasPrefix(setting, "_") {
+		if len(setting) < 9 {
+			return "", fmt.Errorf("invalid crypt setting")
+		}
+		var ok bool
+		if count, ok = cryptDecode(setting[1:5]); !ok || count == 0 {
+			return "", fmt.Errorf("invalid crypt iteration count")
+		}
+		if salt, ok = cryptDecode(setting[5:9]); !ok {
+			return "", fmt.Errorf("invalid crypt salt")
+		}
+		prefix = setting[:9]
+
+		// Each further 8 characters are XORed into the key encrypted under
+		// itself.
+		rest := ""
+		if len(password) > 8 {
+			rest = password[8:]
+		}
+		for len(rest) > 0 {
+			k := encryptBlock(subKeys, binary.BigEndian.Uint64(key[:]))
+			for i := range key {
+				key[i] = byte(k >> (56 - 8*i))
+				if i < len(rest) {
+					key[i] ^= rest[i] << 1
+				}
+			}
+			if len(rest) > 8 {
+				rest = rest[8:]
+			} else {
+				rest = ""
+			}
+			subKeys = newSubKeys(binary.BigEndian.Uint64(key[:]))
+		}
+	} else {
+		var ok bool
+		if len(setting) < 2 {
+			return "", fmt.Errorf("invalid crypt setting")
+		}
+		if salt, ok = cryptDecode(setting[:2]); !ok {
+			return "", fmt.Errorf("invalid crypt salt")
+		}
+		prefix = setting[:2]
+	}
+
+	saltMask := cryptSaltMask(salt)
+	b := uint64(0)
+	for i := uint32(0); i < count; i++ {
+		b = cryptBlock(subKeys, b, expansionFunction[:], saltMask)
+	}
+
+	// The 64 bits of the result and two zero bits, six to a character from
+	// the left.
+	var out strings.Builder
+	out.WriteString(prefix)
+	for shift := 58; shift > -6; shift -= 6 {
+		var c uint64
+		if shift >= 0 {
+			c = b >> shift
+		} else {
+			c = b << -shift
+		}
+		out.WriteByte(cryptAlphabet[c&0x3f])
+	}
+	return out.String(), nil
+}
+
+// VerifyCrypt checks that hash is the crypt(3) hash of password, as
+// returned by Crypt.
+func VerifyCrypt(hash, password string) error {
+	h, err := Crypt(password, hash)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) != 1 {
+		return fmt.Errorf("hash and password do not match")
+	}
+	return nil
+}
+
+// cryptSaltMask returns the mask of cryptBlock for a crypt(3) salt, of
+// which bit i swaps the bits i and i+24 of the expansion, counted from the
+// left.
+func cryptSaltMask(salt uint32) uint32 {
+	var mask uint32
+	for i := 0; i < 24; i++ {
+		if salt>>i&1 != 0 {
+			mask |= 1 << (23 - i)
+		}
+	}
+	return mask
+}
+
+// cryptDecode decodes up to four characters of cryptAlphabet, of which the
+// first is the least significant.
+func cryptDecode(s string) (uint32, bool) {
+	v := uint32(0)
+	for i := len(s) - 1; i >= 0; i-- {
+		n := strings.IndexByte(cryptAlphabet, s[i])
+		if n < 0 {
+			return 0, false
+		}
+		v = v<<6 | uint32(n)
+	}
+	return v, true
+}
@@ -0,0 +1,59 @@
+package des
+
+import "testing"
+
+// The hashes were made by crypt(3) of libxcrypt. "_J9..CCCCXBrJUJV154M" is
+// also the BSDi test vector of FreeSec.
+var cryptTests = []struct {
+	password string
+	hash     string
+}{
+	{"password", "abJnggxhB/yWI"},
+	{"", "..X8NBuQ4l6uQ"},
+	{"test", "zzIUSbhjhhESA"},
+	{"12345678", "XyI8/B3TjQqJg"},
+	// Only the first 8 characters count.
+	{"123456789abc", "XyI8/B3TjQqJg"},
+	{"U*U*U*U*", "CCNf8Sbh3HDfQ"},
+	{"\xe9t\xe9", "1/T2lZjM8kM7g"},
+	{"U*U*U*U*", "_J9..CCCCXBrJUJV154M"},
+	{"", "_J9..CCCCBeguG7nmIew"},
+	{"password", "_J9..CCCC.MOp/ZbelpA"},
+	{"U*U*U*U*U*U*U*U*U*", "_J9..BBBBmepkhbFFuVw"},
+	{"0123456789abcdefghijklmnop", "_K1..crsmdK/pG/0W4Sg"},
+	{"short", "_/...abcdF6nD3ARO7pI"},
+	{"passwordX", "_3...zzzzkDLTZ2fz4Fk"},
+}
+
+func Test_Crypt(t *testing.T) {
+	for i, tt := range cryptTests {
+		got, err := Crypt(tt.password, tt.hash)
+		if err != nil || got != tt.hash {
+			t.Errorf("#%d: Crypt() = %q, %v, want %q", i, got, err, tt.hash)
+		}
+	}
+}
+
+func Test_VerifyCrypt(t *testing.T) {
+	for i, tt := range cryptTests {
+		if err := VerifyCrypt(tt.hash, tt.password); err != nil {
+			t.Errorf("#%d: VerifyCrypt() = %s", i, err)
+		}
+		if err := VerifyCrypt(tt.hash, tt.password+"!"); err == nil && (len(tt.password) < 8 || tt.hash[0] == '_') {
+			t.Errorf("#%d: VerifyCrypt() accepted a wrong password", i)
+		}
+	}
+	if err := VerifyCrypt("abJnggxhB/yWJ", "password"); err == nil {
+		t.Errorf("VerifyCrypt() accepted a wrong hash")
+	}
+}
+
+func Test_Crypt_Invalid(t *testing.T) {
+	for i, setting := range []string{
+		"", "a", "a!", "_J9..CCC", "_....abcd", "_J9.!CCCC", "_J9..CC C",
+	} {
+		if _, err := Crypt("password", setting); err == nil {
+			t.Errorf("#%d: Crypt(%q) succeeded", i, setting)
+		}
+	}
+}
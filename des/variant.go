@@ -0,0 +1,105 @@
+package des
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+)
+
+// VariantParams changes the parts of DES that NewVariantCipher may
+// replace. The zero value is standard DES.
+type VariantParams struct {
+	// Expansion is the E table of the Feistel function: 48 positions of
+	// the bits of the right half, from 1 to 32, the leftmost bit first.
+	// If it is nil, the table of FIPS 46-3 is used.
+	Expansion []uint8
+
+	// Salt perturbs the expansion as crypt(3) does: if bit i of the 24 is
+	// set, the bits i and i+24 of the expansion, counted from the left,
+	// are swapped.
+	Salt uint32
+
+	// Rounds is the number of Feistel rounds, 16 if it is 0. The first
+	// Rounds round keys are used.
+	Rounds int
+
+	// KeySchedule returns the 48-bit round keys of key, in the low bits,
+	// for each round in order. If it is nil, the DES key schedule of an
+	// 8-byte key is used, which gives 16.
+	KeySchedule func(key []byte) ([]uint64, error)
+}
+
+type variantCipher struct {
+	subKeys, invSubKeys []uint64
+	expansion           []uint8
+	saltMask            uint32
+}
+
+// NewVariantCipher creates and returns a new cipher.Block of the variant
+// of DES with parameters p, for analysis and reduced-round experiments.
+// Its variants are not secure; use NewCipher for DES.
+func NewVariantCipher(key []byte, p VariantParams) (cipher.Block, error) {
+	expansion := p.Expansion
+	if expansion == nil {
+		expansion = expansionFunction[:]
+	}
+	if len(expansion) != len(expansionFunction) {
+		return nil, fmt.Errorf("expansion must have %d entries", len(expansionFunction))
+	}
+	for _, n := range expansion {
+		if n < 1 || n > 32 {
+			return nil, fmt.Errorf("invalid expansion entry %d", n)
+		}
+	}
+	if p.Salt >= 1<<24 {
+		return nil, fmt.Errorf("salt must be 24 bits")
+	}
+
+	var subKeys []uint64
+	if p.KeySchedule != nil {
+		var err error
+		if subKeys, err = p.KeySchedule(key); err != nil {
+			return nil, err
+		}
+		for _, k := range subKeys {
+			if k >= 1<<48 {
+				return nil, fmt.Errorf("round keys must be 48 bits")
+			}
+		}
+	} else {
+		if len(key) != 8 {
+			return nil, fmt.Errorf("invalid key size")
+		}
+		subKeys = newSubKeys(binary.BigEndian.Uint64(key))
+	}
+	rounds := p.Rounds
+	if rounds == 0 {
+		rounds = 16
+	}
+	if rounds < 0 || rounds > len(subKeys) {
+		return nil, fmt.Errorf("%d rounds with %d round keys", rounds, len(subKeys))
+	}
+
+	c := &variantCipher{
+		subKeys:    append([]uint64(nil), subKeys[:rounds]...),
+		invSubKeys: make([]uint64, rounds),
+		expansion:  append([]uint8(nil), expansion...),
+		saltMask:   cryptSaltMask(p.Salt),
+	}
+	for i, k := range c.subKeys {
+		c.invSubKeys[rounds-1-i] = k
+	}
+	return c, nil
+}
+
+func (c *variantCipher) BlockSize() int { return BlockSize }
+
+func (c *variantCipher) Encrypt(dst, src []byte) {
+	_, _ = dst[7], src[7] // early bounds check
+	binary.BigEndian.PutUint64(dst[:8], cryptBlock(c.subKeys, binary.BigEndian.Uint64(src[:8]), c.expansion, c.saltMask))
+}
+
+func (c *variantCipher) Decrypt(dst, src []byte) {
+	_, _ = dst[7], src[7] // early bounds check
+	binary.BigEndian.PutUint64(dst[:8], cryptBlock(c.invSubKeys, binary.BigEndian.Uint64(src[:8]), c.expansion, c.saltMask))
+}
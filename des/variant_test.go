@@ -0,0 +1,141 @@
+package des
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var (
+	variantKey   = []byte{0x13, 0x34, 0x57, 0x79, 0x9b, 0xbc, 0xdf, 0xf1}
+	variantPlain = []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+)
+
+// reversedKeySchedule is the DES key schedule in reverse, which makes
+// encryption DES decryption.
+func reversedKeySchedule(key []byte) ([]uint64, error) {
+	k := newSubKeys(binary.BigEndian.Uint64(key))
+	for i, j := 0, len(k)-1; i < j; i, j = i+1, j-1 {
+		k[i], k[j] = k[j], k[i]
+	}
+	return k, nil
+}
+
+// The first value is the DES example of J. Orlin Grabbe's "The DES
+// Algorithm Illustrated". All are recomputed by testdata/variant.py, a DES
+// written bit by bit from FIPS 46-3 with the tables and parameters
+// replaced.
+var variantTests = []struct {
+	params VariantParams
+	enc    string
+}{
+	{VariantParams{}, "85e813540f0ab405"},
+	{VariantParams{Rounds: 1}, "4472457288eeddea"},
+	{VariantParams{Rounds: 4}, "49d8632862d26382"},
+	{VariantParams{Rounds: 8}, "54acc03c4b187449"},
+	{VariantParams{Expansion: []uint8{
+		1, 2, 3, 4, 5, 6, 5, 6, 7, 8, 9, 10, 9, 10, 11, 12,
+		13, 14, 13, 14, 15, 16, 17, 18, 17, 18, 19, 20, 21, 22, 21, 22,
+		23, 24, 25, 26, 25, 26, 27, 28, 29, 30, 29, 30, 31, 32, 1, 2,
+	}}, "0da664e8324567c7"},
+	{VariantParams{Salt: 0x5a5}, "6e95640f36739775"},
+	{VariantParams{Salt: 0xabcdef, Rounds: 8}, "9c499ee5200c7ded"},
+	{VariantParams{KeySchedule: reversedKeySchedule}, "ee0f7c12e0b09338"},
+	{VariantParams{Rounds: 20, KeySchedule: func([]byte) ([]uint64, error) {
+		k := make([]uint64, 20)
+		for i := range k {
+			k[i] = 0x0123456789ab * uint64(i+1) & (1<<48 - 1)
+		}
+		return k, nil
+	}}, "59163202f735c92a"},
+}
+
+func Test_variantCipher(t *testing.T) {
+	for i, tt := range variantTests {
+		c, err := NewVariantCipher(variantKey, tt.params)
+		if err != nil {
+			t.Fatalf("#%d: NewVariantCipher() = %s", i, err)
+		}
+		got := make([]byte, BlockSize)
+		c.Encrypt(got, variantPlain)
+		if want := fmt.Sprintf("%016x", got); want != tt.enc {
+			t.Errorf("#%d: variantCipher.Encrypt() = %s, want %s", i, want, tt.enc)
+		}
+		c.Decrypt(got, got)
+		if !bytes.Equal(got, variantPlain) {
+			t.Errorf("#%d: variantCipher.Decrypt() = %016x, want %016x", i, got, variantPlain)
+		}
+	}
+
+	// The zero parameters are DES.
+	for i, tt := range desCipherTests {
+		c, _ := NewVariantCipher(tt.key, VariantParams{})
+		got := make([]byte, BlockSize)
+		c.Encrypt(got, tt.dec)
+		if !bytes.Equal(got, tt.enc) {
+			t.Errorf("#%d: variantCipher.Encrypt() = %016x, want %016x", i, got, tt.enc)
+		}
+	}
+}
+
+// Test_variantCipher_Salt checks the salt against a hash of crypt(3) by the
+// system libc (libxcrypt), crypt("password", "ab") = "abJnggxhB/yWI": the
+// zero block encrypted 25 times under the password shifted left a bit.
+func Test_variantCipher_Salt(t *testing.T) {
+	const hash = "abJnggxhB/yWI"
+	salt, _ := cryptDecode(hash[:2])
+	var want uint64
+	for i := 2; i < len(hash); i++ {
+		n := uint64(strings.IndexByte(cryptAlphabet, hash[i]))
+		if i < len(hash)-1 {
+			want = want<<6 | n
+		} else {
+			want = want<<4 | n>>2
+		}
+	}
+
+	key := []byte("password")
+	for i := range key {
+		key[i] <<= 1
+	}
+	c, err := NewVariantCipher(key, VariantParams{Salt: salt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, BlockSize)
+	for i := 0; i < 25; i++ {
+		c.Encrypt(b, b)
+	}
+	if got := binary.BigEndian.Uint64(b); got != want {
+		t.Errorf("variantCipher.Encrypt() 25 times = %016x, want %016x", got, want)
+	}
+}
+
+func Test_NewVariantCipher_Invalid(t *testing.T) {
+	bad := append([]uint8(nil), expansionFunction[:]...)
+	bad[5] = 33
+	for i, tt := range []struct {
+		key    []byte
+		params VariantParams
+	}{
+		{variantKey[:7], VariantParams{}},
+		{variantKey, VariantParams{Expansion: expansionFunction[:47]}},
+		{variantKey, VariantParams{Expansion: make([]uint8, 48)}},
+		{variantKey, VariantParams{Expansion: bad}},
+		{variantKey, VariantParams{Salt: 1 << 24}},
+		{variantKey, VariantParams{Rounds: -1}},
+		{variantKey, VariantParams{Rounds: 17}},
+		{variantKey, VariantParams{KeySchedule: func([]byte) ([]uint64, error) {
+			return nil, fmt.Errorf("no keys")
+		}}},
+		{variantKey, VariantParams{Rounds: 1, KeySchedule: func([]byte) ([]uint64, error) {
+			return []uint64{1 << 48}, nil
+		}}},
+	} {
+		if _, err := NewVariantCipher(tt.key, tt.params); err == nil {
+			t.Errorf("#%d: NewVariantCipher() succeeded", i)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+// ANSI X9.24-1-2009: Retail Financial Services Symmetric Key Management, Part 1: Using Symmetric Techniques, Annex A
+// ANSI X9.24-3-2017: Retail Financial Services Symmetric Key Management, Part 3: Derived Unique Key Per Transaction
+
+package main
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+
+	"github.com/AirWSW/go-crypto/aes"
+	"github.com/AirWSW/go-crypto/des"
+)
+
+// DUKPTKeyUsage is what a DUKPT transaction key is derived for.
+//
+// TDES DUKPT derives the key of each usage by XORing a variant into the
+// transaction key. Generation and encryption use the request variants,
+// verification and decryption the response variants, and the both-ways
+// and key encryption usages are not defined.
+type DUKPTKeyUsage int
+
+const (
+	DUKPTPINEncryption DUKPTKeyUsage = iota
+	DUKPTMACGeneration
+	DUKPTMACVerification
+	DUKPTMACBothWays
+	DUKPTDataEncryption
+	DUKPTDataDecryption
+	DUKPTDataBothWays
+	DUKPTKeyEncryption
+)
+
+// dukptUsages are the key usage indicators of the AES DUKPT derivation
+// data.
+var dukptUsages = map[DUKPTKeyUsage]uint16{
+	DUKPTKeyEncryption:   0x0002,
+	DUKPTPINEncryption:   0x1000,
+	DUKPTMACGeneration:   0x2000,
+	DUKPTMACVerification: 0x2001,
+	DUKPTMACBothWays:     0x2002,
+	DUKPTDataEncryption:  0x3000,
+	DUKPTDataDecryption:  0x3001,
+	DUKPTDataBothWays:    0x3002,
+}
+
+const (
+	dukptKeyDerivation        = 0x8000
+	dukptKeyDerivationInitial = 0x8001
+)
+
+// DUKPTKeyType is the algorithm of a key derived by AES DUKPT. Its value is
+// the algorithm indicator of the derivation data.
+type DUKPTKeyType int
+
+const (
+	DUKPT2TDEA DUKPTKeyType = iota
+	DUKPT3TDEA
+	DUKPTAES128
+	DUKPTAES192
+	DUKPTAES256
+)
+
+var dukptKeySizes = [...]int{16, 24, 16, 24, 32}
+
+// tdesDUKPTVariants are the variants XORed into a TDES DUKPT transaction
+// key for each usage.
+var tdesDUKPTVariants = map[DUKPTKeyUsage][16]byte{
+	DUKPTPINEncryption:   {7: 0xff, 15: 0xff},
+	DUKPTMACGeneration:   {6: 0xff, 14: 0xff},
+	DUKPTMACVerification: {4: 0xff, 12: 0xff},
+	DUKPTDataEncryption:  {5: 0xff, 13: 0xff},
+	DUKPTDataDecryption:  {3: 0xff, 11: 0xff},
+}
+
+// tdesDUKPTMask is XORed into a key to derive the left half of the IPEK and
+// of each future key.
+var tdesDUKPTMask = [16]byte{0xc0, 0xc0, 0xc0, 0xc0, 0, 0, 0, 0, 0xc0, 0xc0, 0xc0, 0xc0}
+
+const (
+	// TDESDUKPTKSNSize is the length of a TDES DUKPT key serial number: a
+	// 59-bit initial key serial number and a 21-bit transaction counter.
+	TDESDUKPTKSNSize = 10
+
+	// AESDUKPTKSNSize is the length of an AES DUKPT key serial number: a
+	// 64-bit initial key ID and a 32-bit transaction counter.
+	AESDUKPTKSNSize = 12
+
+	tdesDUKPTCounterMask = 1<<21 - 1
+)
+
+func newTwoKeyTDES(key []byte) (cipher.Block, error) {
+	if len(key) != 16 {
+		return nil, fmt.Errorf("invalid key size")
+	}
+	return des.NewTripleDESCipher(append(append([]byte(nil), key...), key[:8]...))
+}
+
+// TDESDUKPTIPEK derives the initial PIN encryption key of a device from the
+// 16-byte base derivation key and a key serial number of the device, of
+// which the transaction counter is ignored.
+func TDESDUKPTIPEK(bdk, ksn []byte) ([]byte, error) {
+	if len(bdk) != 16 {
+		return nil, fmt.Errorf("invalid key size")
+	}
+	if len(ksn) != TDESDUKPTKSNSize {
+		return nil, fmt.Errorf("invalid KSN length")
+	}
+	var in [8]byte
+	copy(in[:], ksn)
+	in[7] &= 0xe0
+	ipek := make([]byte, 16)
+	for i, mask := range [][]byte{make([]byte, 16), tdesDUKPTMask[:]} {
+		k := make([]byte, 16)
+		xorBytes(k, bdk, mask)
+		block, err := newTwoKeyTDES(k)
+		if err != nil {
+			return nil, err
+		}
+		block.Encrypt(ipek[8*i:], in[:])
+	}
+	return ipek, nil
+}
+
+// TDESDUKPTKey derives the key for usage of the transaction numbered by ksn
+// from the IPEK of the device. The data keys are the variant keys encrypted
+// under themselves, as in the 2009 edition of X9.24-1.
+func TDESDUKPTKey(ipek, ksn []byte, usage DUKPTKeyUsage) ([]byte, error) {
+	if len(ipek) != 16 {
+		return nil, fmt.Errorf("invalid key size")
+	}
+	if len(ksn) != TDESDUKPTKSNSize {
+		return nil, fmt.Errorf("invalid KSN length")
+	}
+	variant, ok := tdesDUKPTVariants[usage]
+	if !ok {
+		return nil, fmt.Errorf("key usage %d is not defined for TDES DUKPT", usage)
+	}
+	reg := binary.BigEndian.Uint64(ksn[2:])
+	counter := reg & tdesDUKPTCounterMask
+	if counter == 0 || bits.OnesCount64(counter) > 10 {
+		return nil, fmt.Errorf("invalid transaction counter")
+	}
+	reg &^= tdesDUKPTCounterMask
+
+	key := append([]byte(nil), ipek...)
+	for bit := uint64(1 << 20); bit != 0; bit >>= 1 {
+		if counter&bit == 0 {
+			continue
+		}
+		reg |= bit
+		var data [8]byte
+		binary.BigEndian.PutUint64(data[:], reg)
+		masked := make([]byte, 16)
+		xorBytes(masked, key, tdesDUKPTMask[:])
+		left, err := tdesDUKPTHalf(masked, data[:])
+		if err != nil {
+			return nil, err
+		}
+		right, err := tdesDUKPTHalf(key, data[:])
+		if err != nil {
+			return nil, err
+		}
+		key = append(left, right...)
+	}
+
+	xorBytes(key, key, variant[:])
+	if usage == DUKPTDataEncryption || usage == DUKPTDataDecryption {
+		block, err := newTwoKeyTDES(key)
+		if err != nil {
+			return nil, err
+		}
+		block.Encrypt(key[:8], key[:8])
+		block.Encrypt(key[8:], key[8:])
+	}
+	return key, nil
+}
+
+// tdesDUKPTHalf is one half of the non-reversible key generation process:
+// DES under the left half of key of data XOR the right half, XORed with the
+// right half again.
+func tdesDUKPTHalf(key, data []byte) ([]byte, error) {
+	block, err := des.NewCipher(key[:8])
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 8)
+	xorBytes(out, data, key[8:])
+	block.Encrypt(out, out)
+	xorBytes(out, out, key[8:])
+	return out, nil
+}
+
+// aesDUKPTKeyType returns the type of an AES derivation key of the length
+// of key.
+func aesDUKPTKeyType(key []byte) (DUKPTKeyType, error) {
+	switch len(key) {
+	case 16:
+		return DUKPTAES128, nil
+	case 24:
+		return DUKPTAES192, nil
+	case 32:
+		return DUKPTAES256, nil
+	}
+	return 0, fmt.Errorf("invalid key size")
+}
+
+// aesDUKPTDerive derives a key of the given type from key and the
+// derivation data for usage, with SP 800-108 counter mode with AES as the
+// PRF. The last 8 bytes of the derivation data are id.
+func aesDUKPTDerive(key []byte, usage uint16, keyType DUKPTKeyType, id []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	size := dukptKeySizes[keyType]
+	var data [aes.BlockSize]byte
+	data[0] = 1
+	binary.BigEndian.PutUint16(data[2:], usage)
+	binary.BigEndian.PutUint16(data[4:], uint16(keyType))
+	binary.BigEndian.PutUint16(data[6:], uint16(size*8))
+	copy(data[8:], id)
+	out := make([]byte, 0, 2*aes.BlockSize)
+	for i := byte(1); len(out) < size; i++ {
+		data[1] = i
+		out = out[:len(out)+aes.BlockSize]
+		block.Encrypt(out[len(out)-aes.BlockSize:], data[:])
+	}
+	return out[:size], nil
+}
+
+// AESDUKPTInitialKey derives the initial key of a device from the 16, 24 or
+// 32-byte base derivation key and the 8-byte initial key ID, the first 8
+// bytes of its key serial numbers.
+func AESDUKPTInitialKey(bdk, initialKeyID []byte) ([]byte, error) {
+	keyType, err := aesDUKPTKeyType(bdk)
+	if err != nil {
+		return nil, err
+	}
+	if len(initialKeyID) != 8 {
+		return nil, fmt.Errorf("invalid initial key ID length")
+	}
+	return aesDUKPTDerive(bdk, dukptKeyDerivationInitial, keyType, initialKeyID)
+}
+
+// AESDUKPTKey derives a key of keyType for usage in the transaction
+// numbered by ksn from the initial key of the device. The key may not be
+// longer than the initial key, and the transaction counter must have from
+// 1 to 16 bits set.
+func AESDUKPTKey(initialKey, ksn []byte, usage DUKPTKeyUsage, keyType DUKPTKeyType) ([]byte, error) {
+	derivationType, err := aesDUKPTKeyType(initialKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(ksn) != AESDUKPTKSNSize {
+		return nil, fmt.Errorf("invalid KSN length")
+	}
+	u, ok := dukptUsages[usage]
+	if !ok {
+		return nil, fmt.Errorf("unknown key usage %d", usage)
+	}
+	if keyType < DUKPT2TDEA || keyType > DUKPTAES256 || dukptKeySizes[keyType] > len(initialKey) {
+		return nil, fmt.Errorf("key type %d cannot be derived from a %d-byte key", keyType, len(initialKey))
+	}
+	counter := binary.BigEndian.Uint32(ksn[8:])
+	if counter == 0 || bits.OnesCount32(counter) > 16 {
+		return nil, fmt.Errorf("invalid transaction counter")
+	}
+
+	key := initialKey
+	id := make([]byte, 8)
+	copy(id, ksn[4:8])
+	var working uint32
+	for bit := uint32(1 << 31); bit != 0; bit >>= 1 {
+		if counter&bit == 0 {
+			continue
+		}
+		working |= bit
+		binary.BigEndian.PutUint32(id[4:], working)
+		if key, err = aesDUKPTDerive(key, dukptKeyDerivation, derivationType, id); err != nil {
+			return nil, err
+		}
+	}
+	return aesDUKPTDerive(key, u, keyType, id)
+}
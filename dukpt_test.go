@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// The TDES vectors extend those of X9.24-1-2009 Annex A.4: BDK
+// 0123456789abcdeffedcba9876543210 and KSN ffff9876543210e0000n, where
+// counter 1 gives PIN key 042666b49184cf5c68de9628d0397b36.
+var tdesDUKPTBDK = hexBytes("0123456789abcdeffedcba9876543210")
+
+var tdesDUKPTTests = []struct {
+	ksn   string
+	usage DUKPTKeyUsage
+	key   string
+}{
+	{"ffff9876543210e00001", DUKPTPINEncryption, "042666b49184cf5c68de9628d0397b36"},
+	{"ffff9876543210e00001", DUKPTMACGeneration, "042666b4918430a368de9628d03984c9"},
+	{"ffff9876543210e00001", DUKPTMACVerification, "042666b46e84cfa368de96282f397bc9"},
+	{"ffff9876543210e00001", DUKPTDataEncryption, "448d3f076d8304036a55a3d7e0055a78"},
+	{"ffff9876543210e00001", DUKPTDataDecryption, "ad7bfc8b06ad3a08a560b4105cf8d9e5"},
+	{"ffff9876543210e00002", DUKPTPINEncryption, "c46551cef9fd244faa9ad834130d3b38"},
+	{"ffff9876543210e00002", DUKPTDataEncryption, "f1be73b36135c5c26cf937d50abbe5af"},
+	{"ffff9876543210e00800", DUKPTPINEncryption, "7e4ab005422bca235f65363964ef65fb"},
+	{"ffff9876543210e00800", DUKPTMACVerification, "7e4ab005bd2bcadc5f6536399bef6504"},
+	{"ffff9876543210fff800", DUKPTPINEncryption, "4124bc9650e70befded3378c9f4e2ebd"},
+	{"ffff9876543210fff800", DUKPTMACGeneration, "4124bc9650e7f410ded3378c9f4ed142"},
+	{"ffff9876543210fff800", DUKPTDataDecryption, "5f2bda11dfb078330adc711210d3d9f7"},
+}
+
+func Test_TDESDUKPTKey(t *testing.T) {
+	ipek, err := TDESDUKPTIPEK(tdesDUKPTBDK, hexBytes("ffff9876543210e00005"))
+	if want := hexBytes("6ac292faa1315b4d858ab3a3d7d5933a"); err != nil || !bytes.Equal(ipek, want) {
+		t.Fatalf("TDESDUKPTIPEK() = %x, %v, want %x", ipek, err, want)
+	}
+	for i, tt := range tdesDUKPTTests {
+		got, err := TDESDUKPTKey(ipek, hexBytes(tt.ksn), tt.usage)
+		if err != nil {
+			t.Errorf("#%d: TDESDUKPTKey() = %s", i, err)
+		} else if want := hexBytes(tt.key); !bytes.Equal(got, want) {
+			t.Errorf("#%d: TDESDUKPTKey() = %x, want %x", i, got, want)
+		}
+	}
+
+	// X9.24-1 Annex A.4: PIN 1234 of account 4012345678909 in an ISO 9564
+	// format 0 PIN block.
+	key, _ := TDESDUKPTKey(ipek, hexBytes("ffff9876543210e00001"), DUKPTPINEncryption)
+	block, _ := newTwoKeyTDES(key)
+	pinBlock := hexBytes("041274edcba9876f")
+	block.Encrypt(pinBlock, pinBlock)
+	if want := hexBytes("1b9c1845eb993a7a"); !bytes.Equal(pinBlock, want) {
+		t.Errorf("encrypted PIN block = %x, want %x", pinBlock, want)
+	}
+}
+
+func Test_TDESDUKPTKey_Invalid(t *testing.T) {
+	ipek, _ := TDESDUKPTIPEK(tdesDUKPTBDK, hexBytes("ffff9876543210e00000"))
+	for i, tt := range []struct {
+		ipek  []byte
+		ksn   string
+		usage DUKPTKeyUsage
+	}{
+		{ipek[:8], "ffff9876543210e00001", DUKPTPINEncryption},
+		{ipek, "ffff9876543210e001", DUKPTPINEncryption},
+		{ipek, "ffff9876543210e00000", DUKPTPINEncryption},
+		// 11 bits set.
+		{ipek, "ffff9876543210effe00", DUKPTPINEncryption},
+		{ipek, "ffff9876543210e00001", DUKPTMACBothWays},
+		{ipek, "ffff9876543210e00001", DUKPTKeyEncryption},
+	} {
+		if _, err := TDESDUKPTKey(tt.ipek, hexBytes(tt.ksn), tt.usage); err == nil {
+			t.Errorf("#%d: TDESDUKPTKey() succeeded", i)
+		}
+	}
+	if _, err := TDESDUKPTIPEK(tdesDUKPTBDK[:8], hexBytes("ffff9876543210e00000")); err == nil {
+		t.Errorf("TDESDUKPTIPEK() accepted an 8-byte BDK")
+	}
+}
+
+// The AES vectors extend those of X9.24-3-2017: BDK
+// fedcba9876543210f1f1f1f1f1f1f1f1 and initial key ID 1234567890123456,
+// where counter 1 gives AES-128 PIN key af8cb133a78f8dc2d1359f18527593fb.
+var aesDUKPTBDK128 = hexBytes("fedcba9876543210f1f1f1f1f1f1f1f1")
+
+var aesDUKPTBDK256 = hexBytes("fedcba9876543210f1f1f1f1f1f1f1f1fedcba9876543210f1f1f1f1f1f1f1f1")
+
+var aesDUKPTTests = []struct {
+	bdk     []byte
+	counter string
+	usage   DUKPTKeyUsage
+	keyType DUKPTKeyType
+	key     string
+}{
+	{aesDUKPTBDK128, "00000001", DUKPTPINEncryption, DUKPTAES128, "af8cb133a78f8dc2d1359f18527593fb"},
+	{aesDUKPTBDK128, "00000001", DUKPTMACGeneration, DUKPTAES128, "a2dc23de6fde0824a2bc321e08e4b8b7"},
+	{aesDUKPTBDK128, "00000001", DUKPTDataBothWays, DUKPTAES128, "a308e080dd15a1b741f1721bf67de11c"},
+	{aesDUKPTBDK128, "00000002", DUKPTPINEncryption, DUKPTAES128, "d30bdc73ec9714b000bec66bdb7b6d09"},
+	{aesDUKPTBDK128, "00000003", DUKPTMACGeneration, DUKPTAES128, "a5df7d9d800ca769766f0c77ca4e6e6c"},
+	{aesDUKPTBDK128, "00000010", DUKPTDataBothWays, DUKPTAES128, "5ef466b1afa1adbc844fac39b7e08f64"},
+	{aesDUKPTBDK128, "ffff0000", DUKPTPINEncryption, DUKPTAES128, "27efac1d158632588f4ac69e45c247c4"},
+	{aesDUKPTBDK128, "ffff0000", DUKPTMACBothWays, DUKPTAES128, "780ab5dae5dc205d84a443f6e1bafd0b"},
+	{aesDUKPTBDK128, "ffff0000", DUKPTDataEncryption, DUKPT2TDEA, "f72fca400c51a2978e9e01c8b77fa5e5"},
+	{aesDUKPTBDK128, "00012345", DUKPTDataEncryption, DUKPT2TDEA, "5d56755e3bedbeb9b7ba3fb363b36045"},
+	{aesDUKPTBDK256, "00000001", DUKPTPINEncryption, DUKPTAES256, "8c1ab7bee973829e30242e0bbbdd4946d540c98fc1b5bdcf94790001a23fd502"},
+	{aesDUKPTBDK256, "00000001", DUKPTPINEncryption, DUKPTAES128, "09c9c432966811d6b2c3336bac1b1202"},
+	{aesDUKPTBDK256, "00000001", DUKPTMACVerification, DUKPT3TDEA, "a881086898a70795f414613d025e155ec83dd79d0ef02dd6"},
+	{aesDUKPTBDK256, "00000001", DUKPTKeyEncryption, DUKPTAES256, "653fd0b1a2d2a75c356248cf265d2d24258527c58b70a394bcac9af01320188d"},
+	{aesDUKPTBDK256, "00012345", DUKPTDataDecryption, DUKPTAES256, "da495e3e0cd9b113613d7ff7655c9bc06c93eeb41a077524138283827d31e008"},
+	{aesDUKPTBDK256, "00012345", DUKPTMACVerification, DUKPTAES128, "a3a0b48f5f9861e622abdff51932658f"},
+	{aesDUKPTBDK256, "00012345", DUKPTKeyEncryption, DUKPT3TDEA, "d823e90dc2b5fe53fdd80826fd9a2e512b94af8afb838eee"},
+}
+
+func Test_AESDUKPTKey(t *testing.T) {
+	ikid := hexBytes("1234567890123456")
+	for _, tt := range []struct {
+		bdk []byte
+		key string
+	}{
+		{aesDUKPTBDK128, "1273671ea26ac29afa4d1084127652a1"},
+		{aesDUKPTBDK256, "ce9ce0c101d1138f97fb6cad4df045a7083d4eae2d35a31789d01ccf0949550f"},
+	} {
+		got, err := AESDUKPTInitialKey(tt.bdk, ikid)
+		if want := hexBytes(tt.key); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("AESDUKPTInitialKey() = %x, %v, want %x", got, err, want)
+		}
+	}
+	for i, tt := range aesDUKPTTests {
+		ik, _ := AESDUKPTInitialKey(tt.bdk, ikid)
+		got, err := AESDUKPTKey(ik, append(ikid, hexBytes(tt.counter)...), tt.usage, tt.keyType)
+		if err != nil {
+			t.Errorf("#%d: AESDUKPTKey() = %s", i, err)
+		} else if want := hexBytes(tt.key); !bytes.Equal(got, want) {
+			t.Errorf("#%d: AESDUKPTKey() = %x, want %x", i, got, want)
+		}
+	}
+}
+
+func Test_AESDUKPTKey_Invalid(t *testing.T) {
+	ikid := hexBytes("1234567890123456")
+	ik, _ := AESDUKPTInitialKey(aesDUKPTBDK128, ikid)
+	for i, tt := range []struct {
+		ik      []byte
+		counter string
+		usage   DUKPTKeyUsage
+		keyType DUKPTKeyType
+	}{
+		{ik[:15], "00000001", DUKPTPINEncryption, DUKPTAES128},
+		{ik, "000001", DUKPTPINEncryption, DUKPTAES128},
+		{ik, "00000000", DUKPTPINEncryption, DUKPTAES128},
+		// 17 bits set.
+		{ik, "ffff8000", DUKPTPINEncryption, DUKPTAES128},
+		{ik, "00000001", DUKPTKeyUsage(99), DUKPTAES128},
+		{ik, "00000001", DUKPTPINEncryption, DUKPTAES256},
+		{ik, "00000001", DUKPTPINEncryption, DUKPT3TDEA},
+		{ik, "00000001", DUKPTPINEncryption, DUKPTKeyType(5)},
+	} {
+		if _, err := AESDUKPTKey(tt.ik, append(ikid, hexBytes(tt.counter)...), tt.usage, tt.keyType); err == nil {
+			t.Errorf("#%d: AESDUKPTKey() succeeded", i)
+		}
+	}
+	if _, err := AESDUKPTInitialKey(aesDUKPTBDK128, ikid[:7]); err == nil {
+		t.Errorf("AESDUKPTInitialKey() accepted a 7-byte initial key ID")
+	}
+}
@@ -0,0 +1,170 @@
+// EMV 4.4 Book 2: Security and Key Management, Annex A1
+// https://www.emvco.com/specifications/
+
+package main
+
+import (
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// EMVICCMasterKeyOptionA derives the ICC master key of a card from the
+// 16-byte issuer master key, its PAN and its 2-digit PAN sequence number,
+// "00" if it is empty, with option A: the rightmost 16 digits of PAN || PSN
+// are encrypted under the issuer master key, and their complement too,
+// and the result is adjusted to odd parity.
+func EMVICCMasterKeyOptionA(imk []byte, pan, psn string) ([]byte, error) {
+	y, err := emvPANPSN(pan, psn)
+	if err != nil {
+		return nil, err
+	}
+	if len(y) > 16 {
+		y = y[len(y)-16:]
+	}
+	return emvMasterKey(imk, strings.Repeat("0", 16-len(y))+y)
+}
+
+// EMVICCMasterKeyOptionB derives the ICC master key as option A does from
+// 16 decimal digits of the SHA-1 hash of PAN || PSN. If PAN || PSN has 16
+// digits or fewer, it is option A, as EMV specifies.
+func EMVICCMasterKeyOptionB(imk []byte, pan, psn string) ([]byte, error) {
+	x, err := emvPANPSN(pan, psn)
+	if err != nil {
+		return nil, err
+	}
+	if len(x) <= 16 {
+		return EMVICCMasterKeyOptionA(imk, pan, psn)
+	}
+	if len(x)%2 != 0 {
+		x = "0" + x
+	}
+	b, _ := hex.DecodeString(x)
+	h := sha1.Sum(b)
+	digits := hex.EncodeToString(h[:])
+
+	// The decimal digits of the hash in order, then if there are fewer than
+	// 16 its other nibbles, A to F, as 0 to 5.
+	y := make([]byte, 0, len(digits))
+	for _, c := range digits {
+		if c <= '9' {
+			y = append(y, byte(c))
+		}
+	}
+	for _, c := range digits {
+		if c > '9' {
+			y = append(y, byte(c-'a'+'0'))
+		}
+	}
+	return emvMasterKey(imk, string(y[:16]))
+}
+
+// emvPANPSN returns PAN || PSN after checking both are decimal.
+func emvPANPSN(pan, psn string) (string, error) {
+	if psn == "" {
+		psn = "00"
+	}
+	if len(pan) < 1 || len(pan) > 19 || !isDigits(pan) {
+		return "", fmt.Errorf("invalid PAN")
+	}
+	if len(psn) != 2 || !isDigits(psn) {
+		return "", fmt.Errorf("invalid PAN sequence number")
+	}
+	return pan + psn, nil
+}
+
+// emvMasterKey encrypts the 16 digits y and their complement under imk and
+// adjusts the result to odd parity.
+func emvMasterKey(imk []byte, y string) ([]byte, error) {
+	block, err := newTwoKeyTDES(imk)
+	if err != nil {
+		return nil, err
+	}
+	key, _ := hex.DecodeString(y + y)
+	for i := 8; i < 16; i++ {
+		key[i] ^= 0xff
+	}
+	block.Encrypt(key[:8], key[:8])
+	block.Encrypt(key[8:], key[8:])
+	for i, b := range key {
+		key[i] = b&0xfe | byte(bits.OnesCount8(b>>1)+1)&1
+	}
+	return key, nil
+}
+
+// EMVCommonSessionKey derives a session key from a 16-byte ICC master key
+// and the 8-byte diversification value r: r with its third byte replaced
+// by F0 and by 0F, encrypted under the master key. For application
+// cryptograms r is the 2-byte ATC followed by six zero bytes.
+func EMVCommonSessionKey(mk, r []byte) ([]byte, error) {
+	if len(r) != 8 {
+		return nil, fmt.Errorf("diversification value must be 8 bytes")
+	}
+	block, err := newTwoKeyTDES(mk)
+	if err != nil {
+		return nil, err
+	}
+	sk := make([]byte, 16)
+	copy(sk, r)
+	copy(sk[8:], r)
+	sk[2], sk[10] = 0xf0, 0x0f
+	block.Encrypt(sk[:8], sk[:8])
+	block.Encrypt(sk[8:], sk[8:])
+	return sk, nil
+}
+
+// EMVApplicationCryptogram returns the application cryptogram, such as an
+// ARQC, of the transaction data under the session key sk: the retail MAC
+// with padding method 2.
+func EMVApplicationCryptogram(sk, data []byte) ([]byte, error) {
+	return RetailMAC(sk, data, 2)
+}
+
+// VerifyEMVARQC checks that arqc is the application cryptogram of the
+// transaction data under the session key sk.
+func VerifyEMVARQC(sk, data, arqc []byte) error {
+	ac, err := EMVApplicationCryptogram(sk, data)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(ac, arqc) != 1 {
+		return fmt.Errorf("ARQC verification failed")
+	}
+	return nil
+}
+
+// EMVARPCMethod1 returns the 8-byte ARPC of method 1: the ARQC XORed with
+// the 2-byte authorisation response code, padded with zeros, encrypted
+// under the session key.
+func EMVARPCMethod1(sk, arqc, arc []byte) ([]byte, error) {
+	if len(arqc) != 8 || len(arc) != 2 {
+		return nil, fmt.Errorf("ARQC must be 8 bytes and ARC 2 bytes")
+	}
+	block, err := newTwoKeyTDES(sk)
+	if err != nil {
+		return nil, err
+	}
+	arpc := make([]byte, 8)
+	copy(arpc, arc)
+	xorBytes(arpc, arpc, arqc)
+	block.Encrypt(arpc, arpc)
+	return arpc, nil
+}
+
+// EMVARPCMethod2 returns the 4-byte ARPC of method 2: the leftmost bytes
+// of the retail MAC of the ARQC, the 4-byte card status update and up to
+// 8 bytes of proprietary authentication data under the session key.
+func EMVARPCMethod2(sk, arqc, csu, proprietary []byte) ([]byte, error) {
+	if len(arqc) != 8 || len(csu) != 4 || len(proprietary) > 8 {
+		return nil, fmt.Errorf("ARQC must be 8 bytes, CSU 4 bytes and proprietary data at most 8 bytes")
+	}
+	msg := append(append(append([]byte(nil), arqc...), csu...), proprietary...)
+	mac, err := RetailMAC(sk, msg, 2)
+	if err != nil {
+		return nil, err
+	}
+	return mac[:4], nil
+}
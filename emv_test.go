@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// No published example values for EMV Book 2 Annex A1 were available. The
+// expected values are recomputed with openssl by testdata/payments/emv.sh.
+var emvIMK = hexBytes("0123456789abcdeffedcba9876543210")
+
+var emvMasterKeyTests = []struct {
+	pan, psn string
+	a, b     string
+}{
+	{"4000000000000002", "01", "577052540b5df22c58ced091858367ef", "bc16295b79abc8319d7f624acb7a9815"},
+	{"5413330089020011", "", "0708c1c2fde685f82064518f29dfe5ce", "0b795b571c324cb52589cd257a52e3cb"},
+	{"12345678901234567", "45", "946dc1a8c78a624519bc4645cb073737", "f767ecc2263b2c543775cb62ab6b57d6"},
+	{"1234567890123456789", "00", "0d7f4c2fd6ced5d029454cf134cda7f7", "2f9275316b4fcb2a52a1cb1610dcaef1"},
+	// Option B is option A for 16 digits or fewer.
+	{"36000000000006", "00", "f79e209b32dab97c5494ce3831070bd5", "f79e209b32dab97c5494ce3831070bd5"},
+}
+
+func Test_EMVICCMasterKey(t *testing.T) {
+	for i, tt := range emvMasterKeyTests {
+		a, err := EMVICCMasterKeyOptionA(emvIMK, tt.pan, tt.psn)
+		if want := hexBytes(tt.a); err != nil || !bytes.Equal(a, want) {
+			t.Errorf("#%d: EMVICCMasterKeyOptionA() = %x, %v, want %x", i, a, err, want)
+		}
+		b, err := EMVICCMasterKeyOptionB(emvIMK, tt.pan, tt.psn)
+		if want := hexBytes(tt.b); err != nil || !bytes.Equal(b, want) {
+			t.Errorf("#%d: EMVICCMasterKeyOptionB() = %x, %v, want %x", i, b, err, want)
+		}
+	}
+	for _, bad := range []struct{ pan, psn string }{
+		{"", "00"}, {"41111111111111111111", "00"}, {"4111-1111", "00"}, {"4111111111111111", "1"}, {"4111111111111111", "0a"},
+	} {
+		if _, err := EMVICCMasterKeyOptionA(emvIMK, bad.pan, bad.psn); err == nil {
+			t.Errorf("EMVICCMasterKeyOptionA(%q, %q) succeeded", bad.pan, bad.psn)
+		}
+	}
+	if _, err := EMVICCMasterKeyOptionB(emvIMK[:8], "4111111111111111", "00"); err == nil {
+		t.Errorf("EMVICCMasterKeyOptionB() accepted an 8-byte key")
+	}
+}
+
+// Test_EMV_ARQC runs an authorisation: session key derivation, ARQC
+// verification and both ARPC methods.
+func Test_EMV_ARQC(t *testing.T) {
+	mk, _ := EMVICCMasterKeyOptionA(emvIMK, "5413330089020011", "00")
+	sk, err := EMVCommonSessionKey(mk, hexBytes("0001000000000000"))
+	if want := hexBytes("6db0b968a8186bbf0d5bce57157a0c01"); err != nil || !bytes.Equal(sk, want) {
+		t.Fatalf("EMVCommonSessionKey() = %x, %v, want %x", sk, err, want)
+	}
+
+	// Amount, other amount, country, TVR, currency, date, type, UN, AIP,
+	// ATC and CVR.
+	data := hexBytes("0000000010000000000000000840000000000008402301019900000001005c00000103a4a000")
+	arqc := hexBytes("a8829e756a07b9f4")
+	if err := VerifyEMVARQC(sk, data, arqc); err != nil {
+		t.Errorf("VerifyEMVARQC() = %s", err)
+	}
+	data[5] ^= 1
+	if err := VerifyEMVARQC(sk, data, arqc); err == nil {
+		t.Errorf("VerifyEMVARQC() accepted modified data")
+	}
+
+	arpc, err := EMVARPCMethod1(sk, arqc, []byte("00"))
+	if want := hexBytes("99d19bc618c82b48"); err != nil || !bytes.Equal(arpc, want) {
+		t.Errorf("EMVARPCMethod1() = %x, %v, want %x", arpc, err, want)
+	}
+	for _, tt := range []struct {
+		proprietary []byte
+		arpc        string
+	}{
+		{nil, "f727bf79"},
+		{hexBytes("1122334455"), "81e62a4e"},
+	} {
+		arpc, err := EMVARPCMethod2(sk, arqc, hexBytes("00820000"), tt.proprietary)
+		if want := hexBytes(tt.arpc); err != nil || !bytes.Equal(arpc, want) {
+			t.Errorf("EMVARPCMethod2(%x) = %x, %v, want %x", tt.proprietary, arpc, err, want)
+		}
+	}
+
+	if _, err := EMVCommonSessionKey(mk, hexBytes("0001")); err == nil {
+		t.Errorf("EMVCommonSessionKey() accepted a 2-byte diversification value")
+	}
+	if _, err := EMVARPCMethod1(sk, arqc, []byte("000")); err == nil {
+		t.Errorf("EMVARPCMethod1() accepted a 3-byte ARC")
+	}
+	if _, err := EMVARPCMethod2(sk, arqc, hexBytes("00820000"), make([]byte, 9)); err == nil {
+		t.Errorf("EMVARPCMethod2() accepted 9 bytes of proprietary data")
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// An encrypted file starts with a header like that of an AEAD stream, a
+// version byte and a random salt, but with its own version so that neither
+// format is opened as the other. It is followed by pages of up to EncryptedFilePageSize
+// bytes of plaintext sealed with AES-GCM. Page i is stored at a fixed offset
+// as
+//
+//	r || ciphertext || tag
+//
+// where r is 8 random bytes and the nonce is [i]32 || r, so the keystream of
+// a page starts at a GCM counter computed from its index alone and any page
+// is read or rewritten without touching the others. A fresh r on every write
+// keeps nonces unique when a page is rewritten in place. The additional data
+// is the header and a flag set only on the last page, which is empty only if
+// the whole file is, so cutting the file is detected.
+const (
+	EncryptedFilePageSize = 4096
+
+	encFileVersion      = 2
+	encFileRandSize     = 8
+	encFilePageOverhead = encFileRandSize + gcmTagSize
+	encFileRecordSize   = EncryptedFilePageSize + encFilePageOverhead
+)
+
+// ReadWriterAt is the interface that groups the ReadAt and WriteAt methods,
+// such as those of *os.File.
+type ReadWriterAt interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// EncryptedFile is a random-access encrypted file stored in a ReadWriterAt.
+// It implements io.ReaderAt and io.WriterAt on the plaintext, authenticating
+// every page it reads.
+//
+// Each page is authenticated on its own, so replacing a page with an older
+// version of the same page is not detected. A write that fails part way may
+// leave the file unreadable. ReadAt may be called concurrently, but WriteAt
+// must not be called concurrently with any other method.
+type EncryptedFile struct {
+	rw     ReadWriterAt
+	aead   cipher.AEAD
+	header [aeadStreamHeader]byte
+	size   int64
+}
+
+// NewEncryptedFile writes a new empty encrypted file to rw, encrypted with
+// the given 16, 24 or 32-byte AES key.
+func NewEncryptedFile(rw ReadWriterAt, key []byte) (*EncryptedFile, error) {
+	f := &EncryptedFile{rw: rw}
+	f.header[0] = encFileVersion
+	if _, err := io.ReadFull(rand.Reader, f.header[1:]); err != nil {
+		return nil, err
+	}
+	var err error
+	if f.aead, err = newSaltedGCM(key, f.header[:]); err != nil {
+		return nil, err
+	}
+	if _, err := rw.WriteAt(f.header[:], 0); err != nil {
+		return nil, err
+	}
+	if err := f.writePage(0, nil, true); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// OpenEncryptedFile opens the encrypted file of the given stored size in rw,
+// such as the size reported by os.File.Stat, with the given AES key. The
+// last page is authenticated to learn the size of the plaintext.
+func OpenEncryptedFile(rw ReadWriterAt, key []byte, size int64) (*EncryptedFile, error) {
+	f := &EncryptedFile{rw: rw}
+	if err := readFullAt(rw, f.header[:], 0); err != nil {
+		return nil, err
+	}
+	if f.header[0] != encFileVersion {
+		return nil, fmt.Errorf("unsupported encrypted file version %d", f.header[0])
+	}
+	var err error
+	if f.aead, err = newSaltedGCM(key, f.header[:]); err != nil {
+		return nil, err
+	}
+
+	body := size - aeadStreamHeader
+	if body < encFilePageOverhead {
+		return nil, fmt.Errorf("truncated encrypted file")
+	}
+	last := (body - encFilePageOverhead) / encFileRecordSize
+	n := body - last*encFileRecordSize - encFilePageOverhead
+	if n == 0 && last > 0 {
+		return nil, fmt.Errorf("truncated encrypted file")
+	}
+	if last >= 1<<32 {
+		return nil, fmt.Errorf("encrypted file too large")
+	}
+	f.size = last*EncryptedFilePageSize + n
+	if _, err := f.readPage(last); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Size returns the size of the plaintext.
+func (f *EncryptedFile) Size() int64 {
+	return f.size
+}
+
+func (f *EncryptedFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset")
+	}
+	n := 0
+	for n < len(p) && off < f.size {
+		i := off / EncryptedFilePageSize
+		pt, err := f.readPage(i)
+		if err != nil {
+			return n, err
+		}
+		m := copy(p[n:], pt[off-i*EncryptedFilePageSize:])
+		n += m
+		off += int64(m)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// WriteAt writes p at offset off of the plaintext, rewriting every page it
+// touches. Writing past the end of the file extends it, filling any gap with
+// zeros.
+func (f *EncryptedFile) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset")
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	end := off + int64(len(p))
+	size := f.size
+	if end > size {
+		size = end
+	}
+	if (size-1)/EncryptedFilePageSize >= 1<<32 {
+		return 0, fmt.Errorf("encrypted file too large")
+	}
+	newLast := (size - 1) / EncryptedFilePageSize
+
+	first := off / EncryptedFilePageSize
+	// When the file grows, the old last page loses its flag and is padded
+	// with zeros along with any pages up to off.
+	if oldLast := f.lastPage(); end > f.size && oldLast < first {
+		first = oldLast
+	}
+
+	n := 0
+	for i := first; i <= (end-1)/EncryptedFilePageSize; i++ {
+		start := i * EncryptedFilePageSize
+		pageEnd := start + EncryptedFilePageSize
+		if pageEnd > size {
+			pageEnd = size
+		}
+		page := make([]byte, pageEnd-start)
+		if start < f.size {
+			old, err := f.readPage(i)
+			if err != nil {
+				return n, err
+			}
+			copy(page, old)
+		}
+		lo, hi := start, pageEnd
+		if lo < off {
+			lo = off
+		}
+		if hi > end {
+			hi = end
+		}
+		if lo < hi {
+			copy(page[lo-start:], p[lo-off:hi-off])
+		}
+
+		if err := f.writePage(i, page, i == newLast); err != nil {
+			return n, err
+		}
+		if pageEnd > f.size {
+			f.size = pageEnd
+		}
+		if hi > off {
+			n = int(hi - off)
+		}
+	}
+	return n, nil
+}
+
+// lastPage returns the index of the last page.
+func (f *EncryptedFile) lastPage() int64 {
+	if f.size == 0 {
+		return 0
+	}
+	return (f.size - 1) / EncryptedFilePageSize
+}
+
+// page returns the nonce and additional data of page i.
+func (f *EncryptedFile) page(i int64, r []byte, last bool) (nonce [gcmNonceSize]byte, ad [aeadStreamHeader + 1]byte) {
+	binary.BigEndian.PutUint32(nonce[:4], uint32(i))
+	copy(nonce[4:], r)
+	copy(ad[:], f.header[:])
+	if last {
+		ad[aeadStreamHeader] = 1
+	}
+	return
+}
+
+// readPage reads and authenticates page i and returns its plaintext.
+func (f *EncryptedFile) readPage(i int64) ([]byte, error) {
+	last := i == f.lastPage()
+	n := int64(EncryptedFilePageSize)
+	if last {
+		n = f.size - i*EncryptedFilePageSize
+	}
+	rec := make([]byte, n+encFilePageOverhead)
+	if err := readFullAt(f.rw, rec, aeadStreamHeader+i*encFileRecordSize); err != nil {
+		return nil, err
+	}
+	nonce, ad := f.page(i, rec[:encFileRandSize], last)
+	return f.aead.Open(rec[encFileRandSize:encFileRandSize], nonce[:], rec[encFileRandSize:], ad[:])
+}
+
+// writePage seals pt as page i under a fresh nonce and writes it.
+func (f *EncryptedFile) writePage(i int64, pt []byte, last bool) error {
+	rec := make([]byte, encFileRandSize, len(pt)+encFilePageOverhead)
+	if _, err := io.ReadFull(rand.Reader, rec); err != nil {
+		return err
+	}
+	nonce, ad := f.page(i, rec, last)
+	rec = f.aead.Seal(rec, nonce[:], pt, ad[:])
+	_, err := f.rw.WriteAt(rec, aeadStreamHeader+i*encFileRecordSize)
+	return err
+}
+
+// readFullAt reads exactly len(p) bytes from r at offset off.
+func readFullAt(r io.ReaderAt, p []byte, off int64) error {
+	n, err := r.ReadAt(p, off)
+	if n == len(p) {
+		return nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
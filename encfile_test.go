@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// memFile is an in-memory ReadWriterAt that grows on writes past its end.
+type memFile struct {
+	b []byte
+}
+
+func (m *memFile) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(m.b)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.b[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (m *memFile) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(m.b) {
+		m.b = append(m.b, make([]byte, end-len(m.b))...)
+	}
+	return copy(m.b[off:], p), nil
+}
+
+func (m *memFile) clone() *memFile {
+	return &memFile{append([]byte(nil), m.b...)}
+}
+
+func Test_EncryptedFile_WriteAt(t *testing.T) {
+	const ps = EncryptedFilePageSize
+	rng := rand.New(rand.NewSource(1))
+	for _, key := range [][]byte{commonKey128, commonKey192, commonKey256} {
+		mf := new(memFile)
+		f, err := NewEncryptedFile(mf, key)
+		if err != nil {
+			t.Fatalf("NewEncryptedFile() = %s", err)
+		}
+		var want []byte
+		writes := []struct{ off, n int }{
+			{0, 10}, {5, 3}, {ps - 1, 2}, {3*ps + 100, 50}, {2 * ps, ps}, {4*ps - 1, 1},
+			{4 * ps, 1}, {0, 4*ps + 1}, {7 * ps, 0}, {6 * ps, ps},
+		}
+		for _, w := range writes {
+			p := make([]byte, w.n)
+			rng.Read(p)
+			if n, err := f.WriteAt(p, int64(w.off)); n != w.n || err != nil {
+				t.Fatalf("%d: WriteAt(%d, %d) = %d, %v", len(key), w.off, w.n, n, err)
+			}
+			if w.n > 0 {
+				if end := w.off + w.n; end > len(want) {
+					want = append(want, make([]byte, end-len(want))...)
+				}
+				copy(want[w.off:], p)
+			}
+
+			if f.Size() != int64(len(want)) {
+				t.Fatalf("%d: Size() = %d, want %d", len(key), f.Size(), len(want))
+			}
+			g, err := OpenEncryptedFile(mf, key, int64(len(mf.b)))
+			if err != nil {
+				t.Fatalf("%d: OpenEncryptedFile() = %s", len(key), err)
+			}
+			got := make([]byte, g.Size())
+			if n, err := g.ReadAt(got, 0); n != len(want) || err != nil || !bytes.Equal(got, want) {
+				t.Fatalf("%d: after WriteAt(%d, %d) ReadAt() = %d, %v", len(key), w.off, w.n, n, err)
+			}
+		}
+
+		// Every range reads back, without decrypting from the start.
+		for i := 0; i < 50; i++ {
+			off := rng.Intn(len(want) + 10)
+			buf := make([]byte, rng.Intn(2*ps))
+			n, err := f.ReadAt(buf, int64(off))
+			wn := 0
+			if off < len(want) {
+				wn = copy(make([]byte, len(buf)), want[off:])
+			}
+			if n != wn || (n < len(buf)) != (err == io.EOF) || !bytes.Equal(buf[:n], want[off:off+n]) {
+				t.Errorf("%d: ReadAt(%d, %d) = %d, %v, want %d", len(key), off, len(buf), n, err, wn)
+			}
+		}
+	}
+}
+
+func Test_EncryptedFile_Rewrite(t *testing.T) {
+	const rec = encFileRecordSize
+	mf := new(memFile)
+	f, err := NewEncryptedFile(mf, commonKey128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteAt(make([]byte, 3*EncryptedFilePageSize), 0)
+	before := mf.clone()
+	f.WriteAt([]byte("rewritten"), EncryptedFilePageSize+10)
+
+	// Only the record of the rewritten page changes, and it gets a new
+	// nonce even when the plaintext is the same.
+	h := aeadStreamHeader
+	if !bytes.Equal(mf.b[:h+rec], before.b[:h+rec]) || !bytes.Equal(mf.b[h+2*rec:], before.b[h+2*rec:]) {
+		t.Errorf("rewriting page 1 changed other pages")
+	}
+	after := mf.clone()
+	f.WriteAt([]byte("rewritten"), EncryptedFilePageSize+10)
+	if bytes.Equal(mf.b[h+rec:h+rec+encFileRandSize], after.b[h+rec:h+rec+encFileRandSize]) {
+		t.Errorf("page rewritten with the same nonce")
+	}
+}
+
+func Test_EncryptedFile_Tamper(t *testing.T) {
+	const rec = encFileRecordSize
+	h := aeadStreamHeader
+	mf := new(memFile)
+	f, err := NewEncryptedFile(mf, commonKey128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteAt(aeadStreamInput(3*EncryptedFilePageSize+5), 0)
+	page := func(b []byte, i int) []byte { return b[h+i*rec : h+(i+1)*rec] }
+
+	swapped := mf.clone()
+	copy(page(swapped.b, 0), page(mf.b, 1))
+	copy(page(swapped.b, 1), page(mf.b, 0))
+	flipped := mf.clone()
+	flipped.b[h+rec+100] ^= 1
+
+	for name, m := range map[string]*memFile{"swapped": swapped, "flipped": flipped} {
+		g, err := OpenEncryptedFile(m, commonKey128, int64(len(m.b)))
+		if err != nil {
+			t.Fatalf("%s: OpenEncryptedFile() = %s", name, err)
+		}
+		buf := make([]byte, 10)
+		if _, err := g.ReadAt(buf, EncryptedFilePageSize+1); err == nil {
+			t.Errorf("%s: ReadAt() accepted a bad page", name)
+		}
+		if _, err := g.WriteAt(buf, EncryptedFilePageSize+1); err == nil {
+			t.Errorf("%s: WriteAt() accepted a bad page", name)
+		}
+		if _, err := g.ReadAt(buf, 2*EncryptedFilePageSize); err != nil {
+			t.Errorf("%s: ReadAt() of a good page = %s", name, err)
+		}
+	}
+
+	for _, size := range []int{h + 3*rec, h + 2*rec, h + rec + 100, h + 10, 5, len(mf.b) + 1} {
+		if _, err := OpenEncryptedFile(mf, commonKey128, int64(size)); err == nil {
+			t.Errorf("OpenEncryptedFile() accepted a file cut to %d bytes", size)
+		}
+	}
+	if _, err := OpenEncryptedFile(mf, commonKey256, int64(len(mf.b))); err == nil {
+		t.Errorf("OpenEncryptedFile() accepted a different key")
+	}
+
+	// An AEAD stream under the same key is rejected by its header.
+	var stream bytes.Buffer
+	w, _ := NewAEADStreamWriter(&stream, commonKey128)
+	w.Write(aeadStreamInput(100))
+	w.Close()
+	sf := &memFile{stream.Bytes()}
+	if _, err := OpenEncryptedFile(sf, commonKey128, int64(len(sf.b))); err == nil || !strings.Contains(err.Error(), "version") {
+		t.Errorf("OpenEncryptedFile() of an AEAD stream = %v", err)
+	}
+	if _, err := NewAEADStreamReader(bytes.NewReader(mf.b), commonKey128); err == nil {
+		t.Errorf("NewAEADStreamReader() accepted an encrypted file")
+	}
+}
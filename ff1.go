@@ -0,0 +1,180 @@
+// NIST SP 800-38G Rev. 1: Methods for Format-Preserving Encryption
+// https://csrc.nist.gov/publications/detail/sp/800-38g/rev-1/draft
+
+package main
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/AirWSW/go-crypto/aes"
+)
+
+// FF1 is the FF1 format-preserving encryption mode of AES. It encrypts
+// strings of numerals in a given radix to strings of the same length and
+// radix, under a tweak of arbitrary length.
+type FF1 struct {
+	block cipher.Block
+	fpeDomain
+	tweak []byte
+}
+
+// NewFF1 creates and returns a new FF1 cipher over the characters of
+// alphabet, whose length is the radix. The key argument should be an AES key
+// and tweak is the default tweak used by Encrypt and Decrypt.
+func NewFF1(key []byte, alphabet string, tweak []byte) (*FF1, error) {
+	d, err := newFPEAlphabet(alphabet)
+	if err != nil {
+		return nil, err
+	}
+	return newFF1(key, d, tweak)
+}
+
+// NewFF1Radix creates and returns a new FF1 cipher over numerals in the given
+// radix, which must be between 2 and 65536. Ciphers created this way have no
+// alphabet and only support EncryptNumerals and DecryptNumerals.
+func NewFF1Radix(key []byte, radix int, tweak []byte) (*FF1, error) {
+	d, err := newFPEDomain(radix)
+	if err != nil {
+		return nil, err
+	}
+	return newFF1(key, d, tweak)
+}
+
+func newFF1(key []byte, d fpeDomain, tweak []byte) (*FF1, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	t := make([]byte, len(tweak))
+	copy(t, tweak)
+	return &FF1{block: block, fpeDomain: d, tweak: t}, nil
+}
+
+// Radix returns the number of distinct numerals.
+func (f *FF1) Radix() int { return f.radix }
+
+// Encrypt encrypts the string x, whose characters must be in the alphabet,
+// under the default tweak.
+func (f *FF1) Encrypt(x string) (string, error) {
+	return f.EncryptWithTweak(x, f.tweak)
+}
+
+// Decrypt decrypts the string x under the default tweak.
+func (f *FF1) Decrypt(x string) (string, error) {
+	return f.DecryptWithTweak(x, f.tweak)
+}
+
+// EncryptWithTweak encrypts the string x under the given tweak.
+func (f *FF1) EncryptWithTweak(x string, tweak []byte) (string, error) {
+	return f.cryptString(x, tweak, false)
+}
+
+// DecryptWithTweak decrypts the string x under the given tweak.
+func (f *FF1) DecryptWithTweak(x string, tweak []byte) (string, error) {
+	return f.cryptString(x, tweak, true)
+}
+
+func (f *FF1) cryptString(x string, tweak []byte, decrypt bool) (string, error) {
+	n, err := f.numerals(x)
+	if err != nil {
+		return "", err
+	}
+	if n, err = f.crypt(n, tweak, decrypt); err != nil {
+		return "", err
+	}
+	return f.string(n), nil
+}
+
+// EncryptNumerals encrypts the numerals x, each less than the radix, under
+// the given tweak.
+func (f *FF1) EncryptNumerals(x []uint16, tweak []byte) ([]uint16, error) {
+	return f.crypt(x, tweak, false)
+}
+
+// DecryptNumerals decrypts the numerals x under the given tweak.
+func (f *FF1) DecryptNumerals(x []uint16, tweak []byte) ([]uint16, error) {
+	return f.crypt(x, tweak, true)
+}
+
+// Algorithm 7: FF1.Encrypt(K, T, X) and Algorithm 8: FF1.Decrypt(K, T, X)
+func (f *FF1) crypt(x []uint16, tweak []byte, decrypt bool) ([]uint16, error) {
+	n, t := len(x), len(tweak)
+	if n < f.minLen() || uint64(n) > 1<<32-1 {
+		return nil, fmt.Errorf("invalid input length")
+	}
+	if uint64(t) > 1<<32-1 {
+		return nil, fmt.Errorf("invalid tweak length")
+	}
+	if err := f.checkNumerals(x); err != nil {
+		return nil, err
+	}
+
+	u := n / 2
+	v := n - u
+	a, b := f.num(x[:u]), f.num(x[u:])
+	mu, mv := f.pow(u), f.pow(v)
+
+	bl := (new(big.Int).Sub(mv, big.NewInt(1)).BitLen() + 7) / 8
+	dl := 4*((bl+3)/4) + 4
+
+	p := []byte{1, 2, 1, 0, 0, 0, 10, byte(u), 0, 0, 0, 0, 0, 0, 0, 0}
+	p[3], p[4], p[5] = byte(f.radix>>16), byte(f.radix>>8), byte(f.radix)
+	binary.BigEndian.PutUint32(p[8:], uint32(n))
+	binary.BigEndian.PutUint32(p[12:], uint32(t))
+
+	q := make([]byte, t+((-t-bl-1)%16+16)%16+1+bl)
+	copy(q, tweak)
+
+	r := make([]byte, aes.BlockSize)
+	s := make([]byte, (dl+aes.BlockSize-1)/aes.BlockSize*aes.BlockSize)
+	y := new(big.Int)
+	for j := 0; j < 10; j++ {
+		i, m := j, mu
+		if decrypt {
+			i = 9 - j
+			a, b = b, a
+		}
+		if i%2 == 1 {
+			m = mv
+		}
+
+		q[len(q)-bl-1] = byte(i)
+		b.FillBytes(q[len(q)-bl:])
+
+		// R = PRF(P || Q) is the last block of a CBC-MAC with a zero IV.
+		copy(r, p)
+		f.block.Encrypt(r, r)
+		for k := 0; k < len(q); k += aes.BlockSize {
+			xorBytes(r, r, q[k:k+aes.BlockSize])
+			f.block.Encrypt(r, r)
+		}
+
+		// S = R || CIPH(R ⊕ [1]) || CIPH(R ⊕ [2]) ... truncated to d bytes.
+		copy(s, r)
+		for k := 1; k < len(s)/aes.BlockSize; k++ {
+			blk := s[k*aes.BlockSize : (k+1)*aes.BlockSize]
+			copy(blk, r)
+			binary.BigEndian.PutUint32(blk[12:], binary.BigEndian.Uint32(r[12:])^uint32(k))
+			f.block.Encrypt(blk, blk)
+		}
+		y.SetBytes(s[:dl])
+
+		if decrypt {
+			a.Sub(a, y)
+		} else {
+			a.Add(a, y)
+		}
+		a.Mod(a, m)
+		if !decrypt {
+			a, b = b, a
+		}
+	}
+
+	out := make([]uint16, n)
+	f.str(out[:u], a)
+	f.str(out[u:], b)
+	return out, nil
+}
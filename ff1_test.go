@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+)
+
+var ff1Key192 = []byte{
+	0x2b, 0x7e, 0x15, 0x16, 0x28, 0xae, 0xd2, 0xa6, 0xab, 0xf7, 0x15, 0x88, 0x09, 0xcf, 0x4f, 0x3c,
+	0xef, 0x43, 0x59, 0xd8, 0xd5, 0x80, 0xaa, 0x4f,
+}
+
+var ff1Key256 = []byte{
+	0x2b, 0x7e, 0x15, 0x16, 0x28, 0xae, 0xd2, 0xa6, 0xab, 0xf7, 0x15, 0x88, 0x09, 0xcf, 0x4f, 0x3c,
+	0xef, 0x43, 0x59, 0xd8, 0xd5, 0x80, 0xaa, 0x4f, 0x7f, 0x03, 0x6d, 0x6f, 0x04, 0xfc, 0x6a, 0x94,
+}
+
+var ff1Tweak10 = []byte{0x39, 0x38, 0x37, 0x36, 0x35, 0x34, 0x33, 0x32, 0x31, 0x30}
+
+var ff1Tweak11 = []byte{0x37, 0x37, 0x37, 0x37, 0x70, 0x71, 0x72, 0x73, 0x37, 0x37, 0x37}
+
+// NIST SP 800-38G FF1 samples
+var ff1Tests = []struct {
+	key      []byte
+	tweak    []byte
+	alphabet string
+	in       string
+	out      string
+}{
+	{commonKey128, nil, DecimalAlphabet, "0123456789", "2433477484"},
+	{commonKey128, ff1Tweak10, DecimalAlphabet, "0123456789", "6124200773"},
+	{commonKey128, ff1Tweak11, AlphanumericAlphabet, "0123456789abcdefghi", "a9tv40mll9kdu509eum"},
+	{ff1Key192, nil, DecimalAlphabet, "0123456789", "2830668132"},
+	{ff1Key192, ff1Tweak10, DecimalAlphabet, "0123456789", "2496655549"},
+	{ff1Key192, ff1Tweak11, AlphanumericAlphabet, "0123456789abcdefghi", "xbj3kv35jrawxv32ysr"},
+	{ff1Key256, nil, DecimalAlphabet, "0123456789", "6657667009"},
+	{ff1Key256, ff1Tweak10, DecimalAlphabet, "0123456789", "1001623463"},
+	{ff1Key256, ff1Tweak11, AlphanumericAlphabet, "0123456789abcdefghi", "xs8a0azh2avyalyzuwd"},
+}
+
+func Test_FF1_Encrypt(t *testing.T) {
+	for i, tt := range ff1Tests {
+		f, err := NewFF1(tt.key, tt.alphabet, tt.tweak)
+		if err != nil {
+			t.Fatalf("#%d: NewFF1() = %s", i, err)
+		}
+		got, err := f.Encrypt(tt.in)
+		if err != nil || got != tt.out {
+			t.Errorf("#%d: FF1.Encrypt() = %q, %v, want %q", i, got, err, tt.out)
+		}
+		got, err = f.Decrypt(tt.out)
+		if err != nil || got != tt.in {
+			t.Errorf("#%d: FF1.Decrypt() = %q, %v, want %q", i, got, err, tt.in)
+		}
+	}
+}
+
+func Test_FF1_Tweak(t *testing.T) {
+	f, _ := NewFF1(commonKey128, DecimalAlphabet, nil)
+	got, err := f.EncryptWithTweak("0123456789", ff1Tweak10)
+	if err != nil || got != "6124200773" {
+		t.Errorf("FF1.EncryptWithTweak() = %q, %v, want %q", got, err, "6124200773")
+	}
+
+	// Numerals beyond any printable alphabet.
+	f, _ = NewFF1Radix(commonKey128, 1<<16, ff1Tweak11)
+	in := []uint16{0, 0xffff, 0x1234, 0x8000}
+	enc, err := f.EncryptNumerals(in, ff1Tweak11)
+	if err != nil {
+		t.Fatalf("FF1.EncryptNumerals() = %s", err)
+	}
+	dec, _ := f.DecryptNumerals(enc, ff1Tweak11)
+	for i := range in {
+		if dec[i] != in[i] {
+			t.Fatalf("FF1.DecryptNumerals() = %x, want %x", dec, in)
+		}
+	}
+	if _, err := f.Encrypt("00"); err == nil {
+		t.Errorf("FF1.Encrypt() without alphabet succeeded")
+	}
+}
+
+func Test_FF1_Invalid(t *testing.T) {
+	if _, err := NewFF1(commonKey128, "0", nil); err == nil {
+		t.Errorf("NewFF1() accepted radix 1")
+	}
+	if _, err := NewFF1(commonKey128, "0120", nil); err == nil {
+		t.Errorf("NewFF1() accepted duplicate characters")
+	}
+	if _, err := NewFF1(commonKey128[:15], DecimalAlphabet, nil); err == nil {
+		t.Errorf("NewFF1() accepted a 15 byte key")
+	}
+	f, _ := NewFF1(commonKey128, DecimalAlphabet, nil)
+	for _, in := range []string{"12345", "01234a6789"} {
+		if out, err := f.Encrypt(in); err == nil {
+			t.Errorf("FF1.Encrypt(%q) = %q, want error", in, out)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// NIST SP 800-38G Rev. 1: Methods for Format-Preserving Encryption
+// https://csrc.nist.gov/publications/detail/sp/800-38g/rev-1/draft
+
+package main
+
+import (
+	"crypto/cipher"
+	"fmt"
+	"math/big"
+
+	"github.com/AirWSW/go-crypto/aes"
+)
+
+// The FF3-1 tweak size in bytes.
+const FF31TweakSize = 7
+
+// FF31 is the FF3-1 format-preserving encryption mode of AES. It encrypts
+// strings of numerals in a given radix to strings of the same length and
+// radix, under a 56-bit tweak.
+type FF31 struct {
+	block cipher.Block
+	fpeDomain
+	tweak []byte
+}
+
+// NewFF31 creates and returns a new FF3-1 cipher over the characters of
+// alphabet, whose length is the radix. The key argument should be an AES key
+// and tweak, which must be FF31TweakSize bytes long, is the default tweak
+// used by Encrypt and Decrypt.
+func NewFF31(key []byte, alphabet string, tweak []byte) (*FF31, error) {
+	d, err := newFPEAlphabet(alphabet)
+	if err != nil {
+		return nil, err
+	}
+	return newFF31(key, d, tweak)
+}
+
+// NewFF31Radix creates and returns a new FF3-1 cipher over numerals in the
+// given radix, which must be between 2 and 65536. Ciphers created this way
+// have no alphabet and only support EncryptNumerals and DecryptNumerals.
+func NewFF31Radix(key []byte, radix int, tweak []byte) (*FF31, error) {
+	d, err := newFPEDomain(radix)
+	if err != nil {
+		return nil, err
+	}
+	return newFF31(key, d, tweak)
+}
+
+func newFF31(key []byte, d fpeDomain, tweak []byte) (*FF31, error) {
+	if len(tweak) != FF31TweakSize {
+		return nil, fmt.Errorf("invalid tweak length")
+	}
+	// FF3 uses the byte reversed key.
+	k := make([]byte, len(key))
+	for i := range key {
+		k[len(k)-1-i] = key[i]
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	t := make([]byte, len(tweak))
+	copy(t, tweak)
+	return &FF31{block: block, fpeDomain: d, tweak: t}, nil
+}
+
+// Radix returns the number of distinct numerals.
+func (f *FF31) Radix() int { return f.radix }
+
+// Encrypt encrypts the string x, whose characters must be in the alphabet,
+// under the default tweak.
+func (f *FF31) Encrypt(x string) (string, error) {
+	return f.EncryptWithTweak(x, f.tweak)
+}
+
+// Decrypt decrypts the string x under the default tweak.
+func (f *FF31) Decrypt(x string) (string, error) {
+	return f.DecryptWithTweak(x, f.tweak)
+}
+
+// EncryptWithTweak encrypts the string x under the given tweak.
+func (f *FF31) EncryptWithTweak(x string, tweak []byte) (string, error) {
+	return f.cryptString(x, tweak, false)
+}
+
+// DecryptWithTweak decrypts the string x under the given tweak.
+func (f *FF31) DecryptWithTweak(x string, tweak []byte) (string, error) {
+	return f.cryptString(x, tweak, true)
+}
+
+func (f *FF31) cryptString(x string, tweak []byte, decrypt bool) (string, error) {
+	n, err := f.numerals(x)
+	if err != nil {
+		return "", err
+	}
+	if n, err = f.crypt(n, tweak, decrypt); err != nil {
+		return "", err
+	}
+	return f.string(n), nil
+}
+
+// EncryptNumerals encrypts the numerals x, each less than the radix, under
+// the given tweak.
+func (f *FF31) EncryptNumerals(x []uint16, tweak []byte) ([]uint16, error) {
+	return f.crypt(x, tweak, false)
+}
+
+// DecryptNumerals decrypts the numerals x under the given tweak.
+func (f *FF31) DecryptNumerals(x []uint16, tweak []byte) ([]uint16, error) {
+	return f.crypt(x, tweak, true)
+}
+
+// maxLen returns 2⌊log_radix(2^96)⌋.
+func (f *FF31) maxLen() int {
+	n := 0
+	for v, max := big.NewInt(int64(f.radix)), new(big.Int).Lsh(big.NewInt(1), 96); v.Cmp(max) <= 0; n++ {
+		v.Mul(v, big.NewInt(int64(f.radix)))
+	}
+	return 2 * n
+}
+
+func (f *FF31) crypt(x []uint16, tweak []byte, decrypt bool) ([]uint16, error) {
+	if len(tweak) != FF31TweakSize {
+		return nil, fmt.Errorf("invalid tweak length")
+	}
+	// The 56-bit tweak is split into two 28-bit halves, each padded to the
+	// 32-bit tweak halves of the original FF3.
+	tl := []byte{tweak[0], tweak[1], tweak[2], tweak[3] & 0xf0}
+	tr := []byte{tweak[4], tweak[5], tweak[6], tweak[3] << 4}
+	return f.crypt3(x, tl, tr, decrypt)
+}
+
+// Algorithm 9: FF3.Encrypt(K, T, X) and Algorithm 10: FF3.Decrypt(K, T, X)
+func (f *FF31) crypt3(x []uint16, tl, tr []byte, decrypt bool) ([]uint16, error) {
+	n := len(x)
+	if n < f.minLen() || n > f.maxLen() {
+		return nil, fmt.Errorf("invalid input length")
+	}
+	if err := f.checkNumerals(x); err != nil {
+		return nil, err
+	}
+
+	// FF3 reads both halves least significant numeral first.
+	u := (n + 1) / 2
+	v := n - u
+	rx := make([]uint16, n)
+	copy(rx, x)
+	a, b := f.num(reverseNumerals(rx[:u])), f.num(reverseNumerals(rx[u:]))
+	mu, mv := f.pow(u), f.pow(v)
+
+	p := make([]byte, aes.BlockSize)
+	y := new(big.Int)
+	for j := 0; j < 8; j++ {
+		i, m, w := j, mu, tr
+		if decrypt {
+			i = 7 - j
+			a, b = b, a
+		}
+		if i%2 == 1 {
+			m, w = mv, tl
+		}
+
+		copy(p, w)
+		p[3] ^= byte(i)
+		b.FillBytes(p[4:])
+
+		// S = REVB(CIPH_REVB(K)(REVB(P)))
+		reverseBytes(p)
+		f.block.Encrypt(p, p)
+		reverseBytes(p)
+		y.SetBytes(p)
+
+		if decrypt {
+			a.Sub(a, y)
+		} else {
+			a.Add(a, y)
+		}
+		a.Mod(a, m)
+		if !decrypt {
+			a, b = b, a
+		}
+	}
+
+	out := make([]uint16, n)
+	f.str(out[:u], a)
+	f.str(out[u:], b)
+	reverseNumerals(out[:u])
+	reverseNumerals(out[u:])
+	return out, nil
+}
+
+// reverseNumerals reverses x in place and returns it.
+func reverseNumerals(x []uint16) []uint16 {
+	for i, j := 0, len(x)-1; i < j; i, j = i+1, j-1 {
+		x[i], x[j] = x[j], x[i]
+	}
+	return x
+}
+
+func reverseBytes(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
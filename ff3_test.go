@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+)
+
+var ff3Key128 = []byte{0xef, 0x43, 0x59, 0xd8, 0xd5, 0x80, 0xaa, 0x4f, 0x7f, 0x03, 0x6d, 0x6f, 0x04, 0xfc, 0x6a, 0x94}
+
+// NIST SP 800-38G FF3 samples, which exercise the FF3 rounds shared with
+// FF3-1 under the original 64-bit tweak.
+var ff3Tests = []struct {
+	tweak    []byte
+	alphabet string
+	in       string
+	out      string
+}{
+	{[]byte{0xd8, 0xe7, 0x92, 0x0a, 0xfa, 0x33, 0x0a, 0x73}, DecimalAlphabet, "890121234567890000", "750918814058654607"},
+	{[]byte{0x9a, 0x76, 0x8a, 0x92, 0xf6, 0x0e, 0x12, 0xd8}, DecimalAlphabet, "890121234567890000", "018989839189395384"},
+	{[]byte{0xd8, 0xe7, 0x92, 0x0a, 0xfa, 0x33, 0x0a, 0x73}, DecimalAlphabet, "89012123456789000000789000000", "48598367162252569629397416226"},
+	{[]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, DecimalAlphabet, "89012123456789000000789000000", "34695224821734535122613701434"},
+	{[]byte{0x9a, 0x76, 0x8a, 0x92, 0xf6, 0x0e, 0x12, 0xd8}, AlphanumericAlphabet[:26], "0123456789abcdefghi", "g2pk40i992fn20cjakb"},
+}
+
+func Test_FF31_crypt3(t *testing.T) {
+	for i, tt := range ff3Tests {
+		f, _ := NewFF31(ff3Key128, tt.alphabet, make([]byte, FF31TweakSize))
+		in, _ := f.numerals(tt.in)
+		out, err := f.crypt3(in, tt.tweak[:4], tt.tweak[4:], false)
+		if err != nil || f.string(out) != tt.out {
+			t.Errorf("#%d: encrypt = %q, %v, want %q", i, f.string(out), err, tt.out)
+			continue
+		}
+		if f.string(in) != tt.in {
+			t.Errorf("#%d: encrypt modified its input", i)
+		}
+		out, err = f.crypt3(out, tt.tweak[:4], tt.tweak[4:], true)
+		if err != nil || f.string(out) != tt.in {
+			t.Errorf("#%d: decrypt = %q, %v, want %q", i, f.string(out), err, tt.in)
+		}
+	}
+}
+
+var ff31Tests = []struct {
+	key   []byte
+	tweak []byte
+	in    string
+	out   string
+}{
+	{
+		[]byte{0x2d, 0xe7, 0x9d, 0x23, 0x2d, 0xf5, 0x58, 0x5d, 0x68, 0xce, 0x47, 0x88, 0x2a, 0xe2, 0x56, 0xd6},
+		[]byte{0xcb, 0xd0, 0x92, 0x80, 0x97, 0x95, 0x64},
+		"3992520240",
+		"8901801106",
+	},
+	{
+		[]byte{0xad, 0x41, 0xec, 0x5d, 0x23, 0x56, 0xde, 0xae, 0x53, 0xae, 0x76, 0xf5, 0x0b, 0x4b, 0xa6, 0xd2},
+		[]byte{0xcf, 0x29, 0xda, 0x1e, 0x18, 0xd9, 0x70},
+		"6520935496",
+		"4716569208",
+	},
+}
+
+func Test_FF31_Encrypt(t *testing.T) {
+	for i, tt := range ff31Tests {
+		f, err := NewFF31(tt.key, DecimalAlphabet, tt.tweak)
+		if err != nil {
+			t.Fatalf("#%d: NewFF31() = %s", i, err)
+		}
+		got, err := f.Encrypt(tt.in)
+		if err != nil || got != tt.out {
+			t.Errorf("#%d: FF31.Encrypt() = %q, %v, want %q", i, got, err, tt.out)
+		}
+		got, err = f.Decrypt(tt.out)
+		if err != nil || got != tt.in {
+			t.Errorf("#%d: FF31.Decrypt() = %q, %v, want %q", i, got, err, tt.in)
+		}
+	}
+}
+
+func Test_FF31_Invalid(t *testing.T) {
+	if _, err := NewFF31(ff3Key128, DecimalAlphabet, make([]byte, 8)); err == nil {
+		t.Errorf("NewFF31() accepted a 64-bit tweak")
+	}
+	f, _ := NewFF31(ff3Key128, DecimalAlphabet, make([]byte, FF31TweakSize))
+	// 2⌊log_10(2^96)⌋ = 56 is the longest decimal input.
+	long := "01234567890123456789012345678901234567890123456789012345"
+	if _, err := f.Encrypt(long); err != nil {
+		t.Errorf("FF31.Encrypt(%d digits) = %s", len(long), err)
+	}
+	for _, in := range []string{"12345", long + "6", "01234a6789"} {
+		if out, err := f.Encrypt(in); err == nil {
+			t.Errorf("FF31.Encrypt(%q) = %q, want error", in, out)
+		}
+	}
+	if _, err := f.EncryptWithTweak("0123456789", make([]byte, 6)); err == nil {
+		t.Errorf("FF31.EncryptWithTweak() accepted a 48-bit tweak")
+	}
+}
@@ -0,0 +1,124 @@
+// NIST SP 800-38G Rev. 1: Methods for Format-Preserving Encryption
+// https://csrc.nist.gov/publications/detail/sp/800-38g/rev-1/draft
+
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"unicode/utf8"
+)
+
+// Alphabets for format-preserving encryption. The radix is the number of
+// characters in the alphabet and each character's numeral is its index.
+const (
+	DecimalAlphabet      = "0123456789"
+	HexAlphabet          = "0123456789abcdef"
+	AlphanumericAlphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+)
+
+// fpeDomain holds the radix and optional alphabet shared by FF1 and FF3-1.
+type fpeDomain struct {
+	radix    int
+	alphabet []rune
+	index    map[rune]uint16
+}
+
+func newFPEDomain(radix int) (fpeDomain, error) {
+	if radix < 2 || radix > 1<<16 {
+		return fpeDomain{}, fmt.Errorf("invalid radix")
+	}
+	return fpeDomain{radix: radix}, nil
+}
+
+func newFPEAlphabet(alphabet string) (fpeDomain, error) {
+	d, err := newFPEDomain(utf8.RuneCountInString(alphabet))
+	if err != nil {
+		return d, fmt.Errorf("invalid alphabet size")
+	}
+	d.alphabet = []rune(alphabet)
+	d.index = make(map[rune]uint16, d.radix)
+	for i, r := range d.alphabet {
+		if _, ok := d.index[r]; ok {
+			return d, fmt.Errorf("duplicate character %q in alphabet", r)
+		}
+		d.index[r] = uint16(i)
+	}
+	return d, nil
+}
+
+// minLen returns the smallest input length for which radix^minLen is at
+// least one million, as required by SP 800-38G Rev. 1.
+func (d fpeDomain) minLen() int {
+	n, v := 0, 1
+	for v < 1000000 {
+		v *= d.radix
+		n++
+	}
+	if n < 2 {
+		n = 2
+	}
+	return n
+}
+
+func (d fpeDomain) checkNumerals(x []uint16) error {
+	for _, c := range x {
+		if int(c) >= d.radix {
+			return fmt.Errorf("numeral out of range")
+		}
+	}
+	return nil
+}
+
+func (d fpeDomain) numerals(s string) ([]uint16, error) {
+	if d.alphabet == nil {
+		return nil, fmt.Errorf("no alphabet for radix %d", d.radix)
+	}
+	x := make([]uint16, 0, len(s))
+	for _, r := range s {
+		c, ok := d.index[r]
+		if !ok {
+			return nil, fmt.Errorf("character not in alphabet")
+		}
+		x = append(x, c)
+	}
+	return x, nil
+}
+
+func (d fpeDomain) string(x []uint16) string {
+	s := make([]rune, len(x))
+	for i, c := range x {
+		s[i] = d.alphabet[c]
+	}
+	return string(s)
+}
+
+// num returns the integer whose radix representation is x, most significant
+// numeral first (NUM_radix in SP 800-38G).
+func (d fpeDomain) num(x []uint16) *big.Int {
+	r := big.NewInt(int64(d.radix))
+	n := new(big.Int)
+	c := new(big.Int)
+	for _, v := range x {
+		n.Mul(n, r)
+		n.Add(n, c.SetInt64(int64(v)))
+	}
+	return n
+}
+
+// str writes the m numeral radix representation of n into x, most
+// significant numeral first (STR^m_radix in SP 800-38G).
+func (d fpeDomain) str(x []uint16, n *big.Int) {
+	r := big.NewInt(int64(d.radix))
+	n = new(big.Int).Set(n)
+	c := new(big.Int)
+	for i := len(x) - 1; i >= 0; i-- {
+		n.DivMod(n, r, c)
+		x[i] = uint16(c.Int64())
+	}
+}
+
+// pow returns radix^m.
+func (d fpeDomain) pow(m int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(int64(d.radix)), big.NewInt(int64(m)), nil)
+}
@@ -0,0 +1,129 @@
+// NIST SP 800-38D: Recommendation for Block Cipher Modes of Operation: Galois/Counter Mode (GCM) and GMAC
+// https://csrc.nist.gov/publications/detail/sp/800-38d/final
+
+package main
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	gcmNonceSize = 12
+	gcmTagSize   = 16
+)
+
+var errOpen = fmt.Errorf("message authentication failed")
+
+type gcm struct {
+	block     cipher.Block
+	h         [GHASHSize]byte
+	nonceSize int
+}
+
+// NewGCM returns the given 128-bit Block, such as one created by
+// aes.NewCipher, wrapped in Galois Counter Mode with the standard 12-byte
+// nonce and 16-byte tag.
+func NewGCM(block cipher.Block) (cipher.AEAD, error) {
+	return NewGCMWithNonceSize(block, gcmNonceSize)
+}
+
+// NewGCMWithNonceSize returns the given 128-bit Block wrapped in Galois
+// Counter Mode with nonces of the given length, which is hashed into the
+// initial counter block unless it is 12 bytes. Only use it for
+// compatibility with existing systems that use non-standard nonce lengths.
+func NewGCMWithNonceSize(block cipher.Block, size int) (cipher.AEAD, error) {
+	if block.BlockSize() != GHASHSize {
+		return nil, fmt.Errorf("GCM requires a 128-bit block cipher")
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid nonce size")
+	}
+	g := &gcm{block: block, nonceSize: size}
+	block.Encrypt(g.h[:], g.h[:])
+	return g, nil
+}
+
+func (g *gcm) NonceSize() int { return g.nonceSize }
+
+func (g *gcm) Overhead() int { return gcmTagSize }
+
+func (g *gcm) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != g.nonceSize {
+		panic("incorrect nonce length given to GCM")
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+gcmTagSize)
+	j0 := gcmCounter(NewGHASH(g.h[:]), nonce)
+
+	g.counterCrypt(out, plaintext, j0)
+	g.tag(out[len(plaintext):], out[:len(plaintext)], additionalData, j0)
+	return ret
+}
+
+func (g *gcm) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != g.nonceSize {
+		panic("incorrect nonce length given to GCM")
+	}
+	if len(ciphertext) < gcmTagSize {
+		return nil, errOpen
+	}
+	tag := ciphertext[len(ciphertext)-gcmTagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-gcmTagSize]
+
+	j0 := gcmCounter(NewGHASH(g.h[:]), nonce)
+	var expected [gcmTagSize]byte
+	g.tag(expected[:], ciphertext, additionalData, j0)
+	if subtle.ConstantTimeCompare(expected[:], tag) != 1 {
+		return nil, errOpen
+	}
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	g.counterCrypt(out, ciphertext, j0)
+	return ret, nil
+}
+
+// counterCrypt XORs src with the keystream of GCTR starting at inc32(j0).
+func (g *gcm) counterCrypt(dst, src []byte, j0 [GHASHSize]byte) {
+	ctr := j0
+	var ks [GHASHSize]byte
+	for len(src) > 0 {
+		binary.BigEndian.PutUint32(ctr[12:], binary.BigEndian.Uint32(ctr[12:])+1)
+		g.block.Encrypt(ks[:], ctr[:])
+		n := xorBytes(dst, src, ks[:])
+		dst = dst[n:]
+		src = src[n:]
+	}
+}
+
+// tag writes GHASH(A || C || [len(A)]64 || [len(C)]64) ⊕ CIPH(J0) to out.
+func (g *gcm) tag(out, ciphertext, additionalData []byte, j0 [GHASHSize]byte) {
+	h := NewGHASH(g.h[:])
+	h.Write(additionalData)
+	h.pad()
+	h.Write(ciphertext)
+	h.pad()
+	var lens [GHASHSize]byte
+	binary.BigEndian.PutUint64(lens[:8], uint64(len(additionalData))*8)
+	binary.BigEndian.PutUint64(lens[8:], uint64(len(ciphertext))*8)
+	h.Write(lens[:])
+	h.Sum(out[:0])
+
+	g.block.Encrypt(j0[:], j0[:])
+	xorBytes(out, out, j0[:])
+}
+
+// sliceForAppend takes a slice and a requested number of bytes. It returns a
+// slice with the contents of the given slice followed by that many bytes and
+// a second slice that aliases into it and contains only the extra bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
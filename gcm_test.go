@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"testing"
+)
+
+// Test_GCM_Seal checks GCM against the standard library for every AES key
+// size, nonces of the standard and other lengths, and a range of plaintext
+// and additional data lengths.
+func Test_GCM_Seal(t *testing.T) {
+	for _, key := range [][]byte{commonKey128, commonKey192, commonKey256} {
+		block := aesBlock(key)
+		for _, ns := range []int{12, 1, 16, 60} {
+			nonce := commonInput[:ns]
+			want, err := cipher.NewGCMWithNonceSize(block, ns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			aead, err := NewGCMWithNonceSize(block, ns)
+			if err != nil {
+				t.Fatalf("NewGCMWithNonceSize() = %s", err)
+			}
+			for _, n := range []int{0, 1, 15, 16, 17, 64} {
+				for _, a := range []int{0, 5, 16, 33} {
+					pt, aad := commonInput[:n], commonInput[64-a:]
+					ct := want.Seal(nil, nonce, pt, aad)
+					if got := aead.Seal(nil, nonce, pt, aad); !bytes.Equal(got, ct) {
+						t.Errorf("%d/%d/%d/%d: Seal() = %x, want %x", len(key), ns, n, a, got, ct)
+					}
+					got, err := aead.Open(nil, nonce, ct, aad)
+					if err != nil || !bytes.Equal(got, pt) {
+						t.Errorf("%d/%d/%d/%d: Open() = %x, %v, want %x", len(key), ns, n, a, got, err, pt)
+					}
+
+					// In place, appending to a prefix.
+					buf := append([]byte("prefix"), pt...)
+					buf = aead.Seal(buf[:6], nonce, buf[6:], aad)
+					if !bytes.Equal(buf[6:], ct) {
+						t.Errorf("%d/%d/%d/%d: in-place Seal() = %x, want %x", len(key), ns, n, a, buf[6:], ct)
+					}
+				}
+			}
+		}
+	}
+}
+
+func Test_GCM_Open(t *testing.T) {
+	aead, err := NewGCM(aesBlock(commonKey128))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := commonInput[:12]
+	ct := aead.Seal(nil, nonce, commonInput, commonInput[:7])
+	for i := range ct {
+		bad := append([]byte(nil), ct...)
+		bad[i] ^= 0x80
+		if _, err := aead.Open(nil, nonce, bad, commonInput[:7]); err == nil {
+			t.Errorf("Open() accepted a ciphertext with byte %d changed", i)
+		}
+	}
+	if _, err := aead.Open(nil, nonce, ct, commonInput[:6]); err == nil {
+		t.Errorf("Open() accepted different additional data")
+	}
+	if _, err := aead.Open(nil, nonce, ct[:15], nil); err == nil {
+		t.Errorf("Open() accepted a ciphertext shorter than the tag")
+	}
+	if _, err := NewGCM(noopBlock(8)); err == nil {
+		t.Errorf("NewGCM() accepted a 64-bit block cipher")
+	}
+	if _, err := NewGCMWithNonceSize(aesBlock(commonKey128), 0); err == nil {
+		t.Errorf("NewGCMWithNonceSize() accepted an empty nonce")
+	}
+}
@@ -0,0 +1,128 @@
+// NIST SP 800-38D: Recommendation for Block Cipher Modes of Operation: Galois/Counter Mode (GCM) and GMAC
+// https://csrc.nist.gov/publications/detail/sp/800-38d/final
+
+package main
+
+import (
+	"encoding/binary"
+)
+
+// The GHASH and POLYVAL block and output size in bytes.
+const GHASHSize = 16
+
+// gcmFieldElement is an element of GF(2^128) in the bit reflected
+// representation of GCM: hi holds the coefficients of x^0 to x^63, with x^0
+// in the most significant bit.
+type gcmFieldElement struct {
+	hi, lo uint64
+}
+
+func gcmFieldElementFromBytes(b []byte) gcmFieldElement {
+	return gcmFieldElement{binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:16])}
+}
+
+func (x gcmFieldElement) putBytes(b []byte) {
+	binary.BigEndian.PutUint64(b[:8], x.hi)
+	binary.BigEndian.PutUint64(b[8:16], x.lo)
+}
+
+// mulX returns x·X, reduced by the GCM polynomial x^128 + x^7 + x^2 + x + 1.
+func (x gcmFieldElement) mulX() gcmFieldElement {
+	lsb := x.lo & 1
+	return gcmFieldElement{
+		hi: x.hi>>1 ^ (0xe100000000000000 & -lsb),
+		lo: x.lo>>1 | x.hi<<63,
+	}
+}
+
+// gcmMul returns x·y following Algorithm 1 of SP 800-38D. It takes the same
+// path for every input so that the running time does not depend on the key
+// or the data.
+func gcmMul(x, y gcmFieldElement) gcmFieldElement {
+	var z gcmFieldElement
+	v := y
+	for i := 0; i < 128; i++ {
+		w := x.hi
+		if i >= 64 {
+			w = x.lo
+		}
+		mask := -(w >> (63 - i%64) & 1)
+		z.hi ^= v.hi & mask
+		z.lo ^= v.lo & mask
+		v = v.mulX()
+	}
+	return z
+}
+
+// GHASH is the universal hash function of GCM keyed by the hash subkey H. It
+// implements hash.Hash; input that does not fill a whole block is padded
+// with zeros when the sum is taken.
+type GHASH struct {
+	h   gcmFieldElement
+	y   gcmFieldElement
+	buf [GHASHSize]byte
+	n   int
+}
+
+// NewGHASH returns a new GHASH keyed by the 16-byte hash subkey h.
+func NewGHASH(h []byte) *GHASH {
+	if len(h) != GHASHSize {
+		panic("invalid GHASH key length")
+	}
+	return &GHASH{h: gcmFieldElementFromBytes(h)}
+}
+
+func (g *GHASH) Size() int { return GHASHSize }
+
+func (g *GHASH) BlockSize() int { return GHASHSize }
+
+func (g *GHASH) Reset() {
+	g.y = gcmFieldElement{}
+	g.n = 0
+}
+
+func (g *GHASH) Write(p []byte) (int, error) {
+	n := len(p)
+	if g.n > 0 {
+		m := copy(g.buf[g.n:], p)
+		g.n += m
+		p = p[m:]
+		if g.n < GHASHSize {
+			return n, nil
+		}
+		g.update(g.buf[:])
+		g.n = 0
+	}
+	for len(p) >= GHASHSize {
+		g.update(p[:GHASHSize])
+		p = p[GHASHSize:]
+	}
+	g.n = copy(g.buf[:], p)
+	return n, nil
+}
+
+// pad completes a partially written block with zeros.
+func (g *GHASH) pad() {
+	if g.n > 0 {
+		for i := g.n; i < GHASHSize; i++ {
+			g.buf[i] = 0
+		}
+		g.update(g.buf[:])
+		g.n = 0
+	}
+}
+
+func (g *GHASH) update(block []byte) {
+	x := gcmFieldElementFromBytes(block)
+	g.y.hi ^= x.hi
+	g.y.lo ^= x.lo
+	g.y = gcmMul(g.y, g.h)
+}
+
+func (g *GHASH) Sum(b []byte) []byte {
+	d := *g
+	d.pad()
+	var out [GHASHSize]byte
+	d.y.putBytes(out[:])
+	return append(b, out[:]...)
+}
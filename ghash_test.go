@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// The GCM Specification, Test Case 2: GHASH(H, {}, C)
+var ghashTests = []struct {
+	h   []byte
+	in  []byte
+	out []byte
+}{
+	{
+		[]byte{0x66, 0xe9, 0x4b, 0xd4, 0xef, 0x8a, 0x2c, 0x3b, 0x88, 0x4c, 0xfa, 0x59, 0xca, 0x34, 0x2b, 0x2e},
+		[]byte{
+			0x03, 0x88, 0xda, 0xce, 0x60, 0xb6, 0xa3, 0x92, 0xf3, 0x28, 0xc2, 0xb9, 0x71, 0xb2, 0xfe, 0x78,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x80,
+		},
+		[]byte{0xf3, 0x8c, 0xbb, 0x1a, 0xd6, 0x92, 0x23, 0xdc, 0xc3, 0x45, 0x7a, 0xe5, 0xb6, 0xb0, 0xf8, 0x85},
+	},
+}
+
+func Test_GHASH_Sum(t *testing.T) {
+	for i, tt := range ghashTests {
+		g := NewGHASH(tt.h)
+		g.Write(tt.in)
+		if got := g.Sum(nil); !bytes.Equal(got, tt.out) {
+			t.Errorf("#%d: GHASH.Sum() = %x, want %x", i, got, tt.out)
+		}
+
+		// Writes of any size must give the same result.
+		for n := 1; n < len(tt.in); n++ {
+			g.Reset()
+			for in := tt.in; len(in) > 0; {
+				m := n
+				if len(in) < m {
+					m = len(in)
+				}
+				g.Write(in[:m])
+				in = in[m:]
+			}
+			if got := g.Sum(nil); !bytes.Equal(got, tt.out) {
+				t.Errorf("#%d/%d: GHASH.Sum() = %x, want %x", i, n, got, tt.out)
+			}
+		}
+	}
+}
+
+func Test_GHASH_Padding(t *testing.T) {
+	g := NewGHASH(ghashTests[0].h)
+	g.Write(commonInput[:20])
+	partial := g.Sum(nil)
+	if again := g.Sum(nil); !bytes.Equal(partial, again) {
+		t.Errorf("GHASH.Sum() changed the state: %x, then %x", partial, again)
+	}
+	g.Reset()
+	g.Write(commonInput[:20])
+	g.Write(make([]byte, 12))
+	if got := g.Sum(nil); !bytes.Equal(got, partial) {
+		t.Errorf("GHASH.Sum() = %x, want zero padded %x", got, partial)
+	}
+}
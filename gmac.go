@@ -0,0 +1,84 @@
+// NIST SP 800-38D: Recommendation for Block Cipher Modes of Operation: Galois/Counter Mode (GCM) and GMAC
+// https://csrc.nist.gov/publications/detail/sp/800-38d/final
+
+package main
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+)
+
+// GMAC is the authentication-only mode of GCM. It implements hash.Hash,
+// authenticating everything written to it as GCM additional data with an
+// empty plaintext, so Sum returns the tag GCM would compute.
+//
+// The IV must be unique for each message authenticated under a key. Reset
+// keeps the IV and must only be used to authenticate the same message again.
+type GMAC struct {
+	ghash GHASH
+	mask  [GHASHSize]byte
+	n     uint64
+}
+
+// NewGMAC returns a GMAC keyed by the given 128-bit Block, such as one
+// created by aes.NewCipher, using the given IV, which must not be empty.
+func NewGMAC(block cipher.Block, iv []byte) (*GMAC, error) {
+	if block.BlockSize() != GHASHSize {
+		return nil, fmt.Errorf("GMAC requires a 128-bit block cipher")
+	}
+	if len(iv) == 0 {
+		return nil, fmt.Errorf("invalid IV length")
+	}
+	m := new(GMAC)
+	var h [GHASHSize]byte
+	block.Encrypt(h[:], h[:])
+	m.ghash.h = gcmFieldElementFromBytes(h[:])
+	j0 := gcmCounter(&m.ghash, iv)
+	block.Encrypt(m.mask[:], j0[:])
+	return m, nil
+}
+
+// gcmCounter returns the pre-counter block J0 for the given IV and leaves
+// g reset.
+func gcmCounter(g *GHASH, iv []byte) [GHASHSize]byte {
+	var j0 [GHASHSize]byte
+	if len(iv) == 12 {
+		copy(j0[:], iv)
+		j0[GHASHSize-1] = 1
+		return j0
+	}
+	g.Write(iv)
+	g.pad()
+	var lens [GHASHSize]byte
+	binary.BigEndian.PutUint64(lens[8:], uint64(len(iv))*8)
+	g.Write(lens[:])
+	g.Sum(j0[:0])
+	g.Reset()
+	return j0
+}
+
+func (m *GMAC) Size() int { return GHASHSize }
+
+func (m *GMAC) BlockSize() int { return GHASHSize }
+
+func (m *GMAC) Reset() {
+	m.ghash.Reset()
+	m.n = 0
+}
+
+func (m *GMAC) Write(p []byte) (int, error) {
+	m.n += uint64(len(p))
+	return m.ghash.Write(p)
+}
+
+func (m *GMAC) Sum(b []byte) []byte {
+	g := m.ghash
+	g.pad()
+	var lens [GHASHSize]byte
+	binary.BigEndian.PutUint64(lens[:8], m.n*8)
+	g.Write(lens[:])
+	tag := g.Sum(nil)
+	xorBytes(tag, tag, m.mask[:])
+	return append(b, tag...)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"testing"
+)
+
+func Test_GMAC_Sum(t *testing.T) {
+	// The GCM Specification, Test Case 1: an empty message under a zero key
+	// and IV.
+	key := make([]byte, 16)
+	want := []byte{0x58, 0xe2, 0xfc, 0xce, 0xfa, 0x7e, 0x30, 0x61, 0x36, 0x7f, 0x1d, 0x57, 0xa4, 0xe7, 0x45, 0x5a}
+	m, err := NewGMAC(aesBlock(key), make([]byte, 12))
+	if err != nil {
+		t.Fatalf("NewGMAC() = %s", err)
+	}
+	if got := m.Sum(nil); !bytes.Equal(got, want) {
+		t.Errorf("GMAC.Sum() = %x, want %x", got, want)
+	}
+}
+
+// Test_GMAC_GCM checks GMAC against the tag of a GCM encryption of an empty
+// plaintext, for IVs of the default and other lengths.
+func Test_GMAC_GCM(t *testing.T) {
+	for _, key := range [][]byte{commonKey128, commonKey192, commonKey256} {
+		block := aesBlock(key)
+		for _, ivLen := range []int{1, 12, 16, 60} {
+			iv := commonInput[:ivLen]
+			aead, err := cipher.NewGCMWithNonceSize(block, ivLen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, n := range []int{0, 1, 15, 16, 17, 64} {
+				aad := commonInput[64-n:]
+				want := aead.Seal(nil, iv, nil, aad)
+
+				m, err := NewGMAC(block, iv)
+				if err != nil {
+					t.Fatalf("NewGMAC() = %s", err)
+				}
+				m.Write(aad)
+				if got := m.Sum(nil); !bytes.Equal(got, want) {
+					t.Errorf("%d/%d/%d: GMAC.Sum() = %x, want %x", len(key), ivLen, n, got, want)
+				}
+				m.Reset()
+				m.Write(aad)
+				if got := m.Sum(nil); !bytes.Equal(got, want) {
+					t.Errorf("%d/%d/%d: GMAC.Sum() after Reset = %x, want %x", len(key), ivLen, n, got, want)
+				}
+			}
+		}
+	}
+}
+
+func Test_GMAC_Invalid(t *testing.T) {
+	if _, err := NewGMAC(aesBlock(commonKey128), nil); err == nil {
+		t.Errorf("NewGMAC() accepted an empty IV")
+	}
+	if _, err := NewGMAC(noopBlock(8), make([]byte, 12)); err == nil {
+		t.Errorf("NewGMAC() accepted a 64-bit block cipher")
+	}
+}
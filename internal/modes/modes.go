@@ -0,0 +1,154 @@
+// NIST SP 800-38A: Recommendation for Block Cipher Modes of Operation: Methods and Techniques
+// https://csrc.nist.gov/publications/detail/sp/800-38a/final
+
+// Package modes implements the modes of operation that crypto/cipher lacks
+// or deprecates, for the root package and the registry.
+package modes
+
+import (
+	"crypto/cipher"
+)
+
+type ecb struct {
+	block   cipher.Block
+	decrypt bool
+}
+
+// NewECB returns a BlockMode that encrypts or decrypts each block on its own.
+func NewECB(block cipher.Block, decrypt bool) cipher.BlockMode {
+	return &ecb{block, decrypt}
+}
+
+func (x *ecb) BlockSize() int { return x.block.BlockSize() }
+
+func (x *ecb) CryptBlocks(dst, src []byte) {
+	bs := x.block.BlockSize()
+	if len(src)%bs != 0 {
+		panic("input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+	for ; len(src) > 0; src, dst = src[bs:], dst[bs:] {
+		if x.decrypt {
+			x.block.Decrypt(dst, src)
+		} else {
+			x.block.Encrypt(dst, src)
+		}
+	}
+}
+
+type cfb struct {
+	block   cipher.Block
+	reg     []byte
+	out     []byte
+	used    int
+	decrypt bool
+}
+
+// NewCFB returns a Stream for CFB mode with segments of a full block, in
+// which each block of ciphertext is encrypted for the next keystream block.
+// It replaces cipher.NewCFBEncrypter and cipher.NewCFBDecrypter, which are
+// deprecated.
+func NewCFB(block cipher.Block, iv []byte, decrypt bool) cipher.Stream {
+	if len(iv) != block.BlockSize() {
+		panic("invalid IV length")
+	}
+	return &cfb{
+		block:   block,
+		reg:     append([]byte(nil), iv...),
+		out:     make([]byte, block.BlockSize()),
+		used:    block.BlockSize(),
+		decrypt: decrypt,
+	}
+}
+
+func (x *cfb) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+	for i, b := range src {
+		if x.used == len(x.out) {
+			x.block.Encrypt(x.out, x.reg)
+			x.used = 0
+		}
+		dst[i] = b ^ x.out[x.used]
+		if x.decrypt {
+			x.reg[x.used] = b
+		} else {
+			x.reg[x.used] = dst[i]
+		}
+		x.used++
+	}
+}
+
+type ofb struct {
+	block cipher.Block
+	out   []byte
+	used  int
+}
+
+// NewOFB returns a Stream for OFB mode, in which the keystream is the IV
+// encrypted again and again. It replaces cipher.NewOFB, which is
+// deprecated.
+func NewOFB(block cipher.Block, iv []byte) cipher.Stream {
+	if len(iv) != block.BlockSize() {
+		panic("invalid IV length")
+	}
+	return &ofb{
+		block: block,
+		out:   append([]byte(nil), iv...),
+		used:  block.BlockSize(),
+	}
+}
+
+func (x *ofb) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+	for i, b := range src {
+		if x.used == len(x.out) {
+			x.block.Encrypt(x.out, x.out)
+			x.used = 0
+		}
+		dst[i] = b ^ x.out[x.used]
+		x.used++
+	}
+}
+
+type cfb8 struct {
+	block   cipher.Block
+	reg     []byte
+	out     []byte
+	decrypt bool
+}
+
+// NewCFB8 returns a Stream for CFB mode with 8-bit segments, which shifts
+// each ciphertext byte into the register.
+func NewCFB8(block cipher.Block, iv []byte, decrypt bool) cipher.Stream {
+	if len(iv) != block.BlockSize() {
+		panic("invalid IV length")
+	}
+	return &cfb8{
+		block:   block,
+		reg:     append([]byte(nil), iv...),
+		out:     make([]byte, block.BlockSize()),
+		decrypt: decrypt,
+	}
+}
+
+func (x *cfb8) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+	for i, b := range src {
+		x.block.Encrypt(x.out, x.reg)
+		dst[i] = b ^ x.out[0]
+		c := dst[i]
+		if x.decrypt {
+			c = b
+		}
+		copy(x.reg, x.reg[1:])
+		x.reg[len(x.reg)-1] = c
+	}
+}
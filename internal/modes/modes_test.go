@@ -0,0 +1,98 @@
+package modes
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+
+	"github.com/AirWSW/go-crypto/aes"
+)
+
+func hexBytes(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+var (
+	testKey   = hexBytes("2b7e151628aed2a6abf7158809cf4f3c")
+	testIV    = hexBytes("000102030405060708090a0b0c0d0e0f")
+	testInput = hexBytes("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710")
+)
+
+// NIST SP 800-38A Appendix F: the AES-128 examples of the stream modes.
+var streamTests = []struct {
+	name   string
+	new    func(block cipher.Block, decrypt bool) cipher.Stream
+	output string
+}{
+	{
+		"CFB8",
+		func(block cipher.Block, decrypt bool) cipher.Stream { return NewCFB8(block, testIV, decrypt) },
+		"3b79424c9c0dd436bace9e0ed4586a4f32b9",
+	},
+	{
+		"CFB",
+		func(block cipher.Block, decrypt bool) cipher.Stream { return NewCFB(block, testIV, decrypt) },
+		"3b3fd92eb72dad20333449f8e83cfb4ac8a64537a0b3a93fcde3cdad9f1ce58b26751f67a3cbb140b1808cf187a4f4dfc04b05357c5d1c0eeac4c66f9ff7f2e6",
+	},
+	{
+		"OFB",
+		func(block cipher.Block, decrypt bool) cipher.Stream { return NewOFB(block, testIV) },
+		"3b3fd92eb72dad20333449f8e83cfb4a7789508d16918f03f53c52dac54ed8259740051e9c5fecf64344f7a82260edcc304c6528f659c77866a510d9c1d6ae5e",
+	},
+	{
+		"CTR",
+		func(block cipher.Block, decrypt bool) cipher.Stream {
+			return NewCTR(block, hexBytes("f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff"))
+		},
+		"874d6191b620e3261bef6864990db6ce9806f66b7970fdff8617187bb9fffdff5ae4df3edbd5d35e5b4f09020db03eab1e031dda2fbe03d1792170a0f3009cee",
+	},
+}
+
+// Test_Streams encrypts and decrypts in one call and in pieces of 1, 4,
+// 7, ... bytes.
+func Test_Streams(t *testing.T) {
+	block, _ := aes.NewCipher(testKey)
+	for _, tt := range streamTests {
+		want := hexBytes(tt.output)
+		in := testInput[:len(want)]
+		for _, decrypt := range []bool{false, true} {
+			src, dst := in, want
+			if decrypt {
+				src, dst = want, in
+			}
+			for _, step := range []int{len(src), 1} {
+				s := tt.new(block, decrypt)
+				got := make([]byte, len(src))
+				for i, n := 0, step; i < len(src); i, n = i+n, n+3 {
+					end := i + n
+					if end > len(src) {
+						end = len(src)
+					}
+					s.XORKeyStream(got[i:end], src[i:end])
+				}
+				if !bytes.Equal(got, dst) {
+					t.Errorf("%s: XORKeyStream(decrypt %v, first piece %d) = %x, want %x", tt.name, decrypt, step, got, dst)
+				}
+			}
+		}
+	}
+}
+
+func Test_ECB(t *testing.T) {
+	block, _ := aes.NewCipher(testKey)
+	want := hexBytes("3ad77bb40d7a3660a89ecaf32466ef97f5d3d58503b9699de785895a96fdbaaf43b1cd7f598ece23881b00e3ed0306887b0c785e27e8ad3f8223207104725dd4")
+	got := make([]byte, len(testInput))
+	NewECB(block, false).CryptBlocks(got, testInput)
+	if !bytes.Equal(got, want) {
+		t.Errorf("ECB encrypt = %x, want %x", got, want)
+	}
+	NewECB(block, true).CryptBlocks(got, want)
+	if !bytes.Equal(got, testInput) {
+		t.Errorf("ECB decrypt = %x, want %x", got, testInput)
+	}
+}
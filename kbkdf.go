@@ -0,0 +1,181 @@
+// NIST SP 800-108r1: Recommendation for Key Derivation Using Pseudorandom Functions
+// https://csrc.nist.gov/publications/detail/sp/800-108/rev-1/final
+
+package main
+
+import (
+	"crypto/cipher"
+	"fmt"
+	"hash"
+
+	"github.com/AirWSW/go-crypto/aes"
+	"github.com/AirWSW/go-crypto/des"
+)
+
+// KBKDFMode is how KBKDF chains the PRF invocations.
+type KBKDFMode int
+
+const (
+	// KBKDFCounter computes K(i) = PRF(KI, [i] || fixed input).
+	KBKDFCounter KBKDFMode = iota
+
+	// KBKDFFeedback computes K(i) = PRF(KI, K(i-1) || [i] || fixed input)
+	// with K(0) = IV.
+	KBKDFFeedback
+
+	// KBKDFDoublePipeline computes A(i) = PRF(KI, A(i-1)) with A(0) = fixed
+	// input, and K(i) = PRF(KI, A(i) || [i] || fixed input).
+	KBKDFDoublePipeline
+)
+
+// KBKDFCounterLocation is where the counter [i] goes in the PRF input.
+type KBKDFCounterLocation int
+
+const (
+	// KBKDFCounterFirst puts the counter before everything else: before the
+	// fixed input in counter mode, before K(i-1) or A(i) in the others.
+	KBKDFCounterFirst KBKDFCounterLocation = iota
+
+	// KBKDFCounterAfterIter puts it after K(i-1) or A(i), before the fixed
+	// input. In counter mode it is the same as KBKDFCounterFirst.
+	KBKDFCounterAfterIter
+
+	// KBKDFCounterLast puts it after the fixed input.
+	KBKDFCounterLast
+
+	// KBKDFCounterMiddle puts it CounterOffset bytes into the fixed input.
+	KBKDFCounterMiddle
+)
+
+// KBKDF is a key-based key derivation function of SP 800-108 with the
+// given PRF, mode and counter encoding.
+type KBKDF struct {
+	// PRF returns the pseudorandom function keyed by key, such as
+	// KBKDFAESCMAC.
+	PRF func(key []byte) (hash.Hash, error)
+
+	Mode KBKDFMode
+
+	// CounterSize is the length of the big-endian counter [i] in bytes,
+	// from 1 to 4. It may be 0 in feedback and double-pipeline mode, for
+	// no counter.
+	CounterSize     int
+	CounterLocation KBKDFCounterLocation
+	CounterOffset   int
+
+	// IV is K(0) in feedback mode. It may be empty.
+	IV []byte
+}
+
+// KBKDFAESCMAC is the PRF of AES-CMAC, with a 16, 24 or 32-byte key.
+func KBKDFAESCMAC(key []byte) (hash.Hash, error) {
+	return newCMACPRF(aes.NewCipher, key)
+}
+
+// KBKDFTDESCMAC is the PRF of 3DES-CMAC, with a 16-byte two-key (k1 k2 k1)
+// or 24-byte three-key 3DES key.
+func KBKDFTDESCMAC(key []byte) (hash.Hash, error) {
+	return newCMACPRF(func(key []byte) (cipher.Block, error) {
+		if len(key) == 16 {
+			key = append(append([]byte(nil), key...), key[:8]...)
+		}
+		return des.NewTripleDESCipher(key)
+	}, key)
+}
+
+func newCMACPRF(newBlock func(key []byte) (cipher.Block, error), key []byte) (hash.Hash, error) {
+	block, err := newBlock(key)
+	if err != nil {
+		return nil, err
+	}
+	return NewCMAC(block)
+}
+
+// KBKDFFixedInput returns the fixed input Label || 0x00 || Context || [L]
+// recommended by SP 800-108, where [L] is length, the number of bits to
+// derive, big endian in lengthSize bytes.
+func KBKDFFixedInput(label, context []byte, length, lengthSize int) []byte {
+	b := append(append([]byte(nil), label...), 0)
+	b = append(b, context...)
+	for i := lengthSize - 1; i >= 0; i-- {
+		b = append(b, byte(uint64(length)>>(8*i)))
+	}
+	return b
+}
+
+// Derive derives length bytes from key and the fixed input, which is
+// usually made by KBKDFFixedInput.
+func (k *KBKDF) Derive(key, fixedInput []byte, length int) ([]byte, error) {
+	if k.CounterSize < 0 || k.CounterSize > 4 || k.CounterSize == 0 && k.Mode == KBKDFCounter {
+		return nil, fmt.Errorf("invalid counter size %d", k.CounterSize)
+	}
+	if k.Mode < KBKDFCounter || k.Mode > KBKDFDoublePipeline {
+		return nil, fmt.Errorf("invalid KBKDF mode")
+	}
+	switch k.CounterLocation {
+	case KBKDFCounterFirst, KBKDFCounterAfterIter, KBKDFCounterLast:
+	case KBKDFCounterMiddle:
+		if k.CounterOffset < 0 || k.CounterOffset > len(fixedInput) {
+			return nil, fmt.Errorf("counter offset %d outside the fixed input", k.CounterOffset)
+		}
+	default:
+		return nil, fmt.Errorf("invalid counter location")
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("invalid length")
+	}
+	prf, err := k.PRF(key)
+	if err != nil {
+		return nil, err
+	}
+	h := prf.Size()
+	n := uint64((length + h - 1) / h)
+	if k.CounterSize > 0 && n > 1<<(8*k.CounterSize)-1 || n > 1<<32-1 {
+		return nil, fmt.Errorf("cannot derive %d bytes with a %d-byte counter", length, k.CounterSize)
+	}
+
+	out := make([]byte, 0, int(n)*h)
+	var iter []byte
+	switch k.Mode {
+	case KBKDFFeedback:
+		iter = k.IV
+	case KBKDFDoublePipeline:
+		iter = fixedInput
+	}
+	ctr := make([]byte, k.CounterSize)
+	for i := uint64(1); i <= n; i++ {
+		for j := range ctr {
+			ctr[j] = byte(i >> (8 * (len(ctr) - 1 - j)))
+		}
+		if k.Mode == KBKDFDoublePipeline {
+			prf.Reset()
+			prf.Write(iter)
+			iter = prf.Sum(nil)
+		}
+		prf.Reset()
+		switch k.CounterLocation {
+		case KBKDFCounterFirst:
+			prf.Write(ctr)
+			prf.Write(iter)
+			prf.Write(fixedInput)
+		case KBKDFCounterAfterIter:
+			prf.Write(iter)
+			prf.Write(ctr)
+			prf.Write(fixedInput)
+		case KBKDFCounterLast:
+			prf.Write(iter)
+			prf.Write(fixedInput)
+			prf.Write(ctr)
+		case KBKDFCounterMiddle:
+			prf.Write(iter)
+			prf.Write(fixedInput[:k.CounterOffset])
+			prf.Write(ctr)
+			prf.Write(fixedInput[k.CounterOffset:])
+		}
+		out = prf.Sum(out)
+		if k.Mode == KBKDFFeedback {
+			iter = out[len(out)-h:]
+		}
+	}
+	return out[:length], nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"hash"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The files in testdata/kbkdf are the CMAC sections of the SP 800-108 KBKDF
+// vectors of the CAVP, cut to three vectors per group.
+var kbkdfFiles = []struct {
+	file string
+	mode KBKDFMode
+}{
+	{"KDFCTR.rsp", KBKDFCounter},
+	{"KDFFeedback_iv.rsp", KBKDFFeedback},
+	{"KDFFeedback_zeroiv.rsp", KBKDFFeedback},
+	{"KDFFeedback_nocounter.rsp", KBKDFFeedback},
+	{"KDFDblPipeline.rsp", KBKDFDoublePipeline},
+	{"KDFDblPipeline_nocounter.rsp", KBKDFDoublePipeline},
+}
+
+var kbkdfPRFs = map[string]func(key []byte) (hash.Hash, error){
+	"CMAC_AES128": KBKDFAESCMAC,
+	"CMAC_AES192": KBKDFAESCMAC,
+	"CMAC_AES256": KBKDFAESCMAC,
+	"CMAC_TDES2":  KBKDFTDESCMAC,
+	"CMAC_TDES3":  KBKDFTDESCMAC,
+}
+
+var kbkdfLocations = map[string]KBKDFCounterLocation{
+	"BEFORE_FIXED": KBKDFCounterFirst,
+	"BEFORE_ITER":  KBKDFCounterFirst,
+	"AFTER_ITER":   KBKDFCounterAfterIter,
+	"AFTER_FIXED":  KBKDFCounterLast,
+	"MIDDLE_FIXED": KBKDFCounterMiddle,
+}
+
+func Test_KBKDF_CAVP(t *testing.T) {
+	for _, tt := range kbkdfFiles {
+		f, err := os.Open(filepath.Join("testdata", "kbkdf", tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := parseCAVP(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %s", tt.file, err)
+		}
+		for _, r := range records {
+			k := &KBKDF{Mode: tt.mode, PRF: kbkdfPRFs[r.params["PRF"]]}
+			if k.PRF == nil {
+				t.Fatalf("%s:%d: unknown PRF %q", tt.file, r.line, r.params["PRF"])
+			}
+			if rlen, ok := r.params["RLEN"]; ok {
+				bits, err := strconv.Atoi(strings.TrimSuffix(rlen, "_BITS"))
+				if err != nil {
+					t.Fatalf("%s:%d: invalid RLEN %q", tt.file, r.line, rlen)
+				}
+				k.CounterSize = bits / 8
+				k.CounterLocation = kbkdfLocations[r.params["CTRLOCATION"]]
+			}
+			field := func(name string) []byte {
+				b, err := cavpField(r, name)
+				if err != nil {
+					t.Fatalf("%s:%d: %s", tt.file, r.line, err)
+				}
+				return b
+			}
+			l, err := strconv.Atoi(r.fields["L"])
+			if err != nil {
+				t.Fatalf("%s:%d: invalid L: %s", tt.file, r.line, err)
+			}
+			ki, ko := field("KI"), field("KO")
+			var fixed []byte
+			if k.CounterLocation == KBKDFCounterMiddle {
+				before := field("DataBeforeCtrData")
+				fixed = append(before, field("DataAfterCtrData")...)
+				k.CounterOffset = len(before)
+			} else {
+				fixed = field("FixedInputData")
+			}
+			if tt.mode == KBKDFFeedback {
+				k.IV = field("IV")
+			}
+
+			got, err := k.Derive(ki, fixed, l/8)
+			if err != nil {
+				t.Errorf("%s:%d: Derive() = %s", tt.file, r.line, err)
+			} else if !bytes.Equal(got, ko) {
+				t.Errorf("%s:%d: Derive() = %x, want %x", tt.file, r.line, got, ko)
+			}
+		}
+	}
+}
+
+// Test_KBKDF_Derive checks that the output is a prefix of a longer output,
+// and the fixed input encoding.
+func Test_KBKDF_Derive(t *testing.T) {
+	fixed := KBKDFFixedInput([]byte("label"), []byte("context"), 0x1234, 3)
+	if want := []byte("label\x00context\x00\x12\x34"); !bytes.Equal(fixed, want) {
+		t.Errorf("KBKDFFixedInput() = %x, want %x", fixed, want)
+	}
+	for _, k := range []*KBKDF{
+		{PRF: KBKDFAESCMAC, Mode: KBKDFCounter, CounterSize: 1},
+		{PRF: KBKDFAESCMAC, Mode: KBKDFFeedback, CounterSize: 2, CounterLocation: KBKDFCounterLast, IV: commonCounter},
+		{PRF: KBKDFTDESCMAC, Mode: KBKDFDoublePipeline, CounterLocation: KBKDFCounterMiddle, CounterOffset: 5},
+	} {
+		key := commonKey128
+		long, err := k.Derive(key, fixed, 50)
+		if err != nil {
+			t.Fatalf("Derive() = %s", err)
+		}
+		for _, n := range []int{0, 1, 8, 16, 17, 49} {
+			if got, _ := k.Derive(key, fixed, n); !bytes.Equal(got, long[:n]) {
+				t.Errorf("Derive(%d) = %x, want %x", n, got, long[:n])
+			}
+		}
+	}
+}
+
+func Test_KBKDF_Invalid(t *testing.T) {
+	fixed := []byte("fixed input")
+	for i, tt := range []struct {
+		k      KBKDF
+		key    []byte
+		length int
+	}{
+		{KBKDF{PRF: KBKDFAESCMAC, Mode: KBKDFCounter}, commonKey128, 16},
+		{KBKDF{PRF: KBKDFAESCMAC, Mode: KBKDFCounter, CounterSize: 5}, commonKey128, 16},
+		{KBKDF{PRF: KBKDFAESCMAC, Mode: 3, CounterSize: 1}, commonKey128, 16},
+		{KBKDF{PRF: KBKDFAESCMAC, CounterSize: 1, CounterLocation: KBKDFCounterMiddle, CounterOffset: 12}, commonKey128, 16},
+		{KBKDF{PRF: KBKDFAESCMAC, CounterSize: 1}, commonKey128[:15], 16},
+		{KBKDF{PRF: KBKDFTDESCMAC, CounterSize: 1}, commonKey128[:8], 16},
+		{KBKDF{PRF: KBKDFAESCMAC, CounterSize: 1}, commonKey128, -1},
+		// 255 blocks is the most an 8-bit counter allows.
+		{KBKDF{PRF: KBKDFAESCMAC, CounterSize: 1}, commonKey128, 255*16 + 1},
+	} {
+		if _, err := tt.k.Derive(tt.key, fixed, tt.length); err == nil {
+			t.Errorf("#%d: Derive() succeeded", i)
+		}
+	}
+}
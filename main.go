@@ -1,68 +1,319 @@
+// Command go-crypto encrypts and decrypts data with the block ciphers and
+// modes of operation of this module.
+//
+// Usage:
+//
+//	go-crypto encrypt [flags] [file ...]
+//	go-crypto decrypt [flags] [file ...]
+//	go-crypto encrypt -pass arg [-alg aes|3des] [-kdf scrypt|pbkdf2|argon2id] [file ...]
+//	go-crypto decrypt -pass arg [file ...]
+//	go-crypto cavp [-q] file.rsp ...
+//	go-crypto timing [-n measurements] [-seed n] [target ...]
+//	go-crypto bench [-run regexp] [-benchtime d]
+//	go-crypto ciphers
+//	go-crypto enc [-d] -cipher name -pass arg [flags] [file ...]
+//
+// For encrypt and decrypt the input is the concatenation of the named files,
+// or standard input if there are none or a file is named "-". The output is
+// written to standard output. With -pass the key is derived from a password
+// and the output of encrypt starts with a header that names the cipher, the
+// key derivation and its salt; decrypt needs only the password.
+//
+// The cavp command runs the test vectors of NIST CAVP response files, such
+// as those of AESAVS and TMOVS, and exits with status 1 if any fails. The
+// cipher and mode are taken from the file name.
+//
+// The timing command tests the Encrypt and Decrypt methods of the ciphers for
+// running times that depend on the input, after dudect, and exits with
+// status 1 if any does. The -l flag lists the targets.
+//
+// The bench command runs the benchmarks of key setup, single-block Encrypt
+// and Decrypt, and CTR mode at several buffer sizes for each cipher, and
+// prints the time, throughput and allocations per operation.
+//
+// The ciphers command lists the ciphers of the registry with their key, IV
+// and block sizes in bytes.
+//
+// The enc command reads and writes the format of openssl enc, with the key
+// and IV derived from a password by EVP_BytesToKey or PBKDF2. Its flags
+// follow those of openssl enc, except that the cipher is given by -cipher.
 package main
 
 import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/AirWSW/go-crypto/aes"
 	"github.com/AirWSW/go-crypto/des"
+	"github.com/AirWSW/go-crypto/registry"
 )
 
+type command struct {
+	name  string
+	short string
+	run   func(args []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{"encrypt", "encrypt data", cryptCommand("encrypt", false)},
+	{"decrypt", "decrypt data", cryptCommand("decrypt", true)},
+	{"cavp", "run NIST CAVP response files", cavpCommand},
+	{"timing", "test the ciphers for timing leaks", timingCommand},
+	{"bench", "benchmark the ciphers and CTR mode", benchCommand},
+	{"ciphers", "list the registered ciphers", ciphersCommand},
+	{"enc", "encrypt or decrypt in the format of openssl enc", opensslCommand},
+}
+
+// errUsage is returned by a command whose flags could not be parsed; the
+// flag package has already reported the problem.
+var errUsage = errors.New("usage")
+
 func main() {
-	fmt.Printf(" DES CTR %x\n", desCTR(
-		[]byte{0x6e, 0x5e, 0xe2, 0x47, 0xc4, 0xbf, 0xf6, 0x51},
-		[]byte{0xa3, 0xc2, 0x60, 0xb1, 0x0b, 0xb7, 0x28, 0x6e},
-		[]byte{
-			0x6b, 0xc1, 0xbe, 0xe2, 0x2e, 0x40, 0x9f, 0x96, 0xe9, 0x3d, 0x7e, 0x11, 0x73, 0x93, 0x17, 0x2a,
-			0xae, 0x2d, 0x8a, 0x57, 0x1e, 0x03, 0xac, 0x9c, 0x9e, 0xb7, 0x6f, 0xac, 0x45, 0xaf, 0x8e, 0x51,
-			0x30, 0xc8, 0x1c, 0x46, 0xa3, 0x5c, 0xe4, 0x11, 0xe5, 0xfb, 0xc1, 0x19, 0x1a, 0x0a, 0x52, 0xef,
-			0xf6, 0x9f, 0x24, 0x45, 0xdf, 0x4f, 0x9b, 0x17, 0xad, 0x2b, 0x41, 0x7b, 0xe6, 0x6c, 0x37, 0x10,
-		},
-	))
-	fmt.Printf("3DES CTR %x\n", tripleDESCTR(
-		[]byte{
-			0xcb, 0x10, 0x7d, 0xda, 0x7e, 0x96, 0x57, 0x0a,
-			0xe8, 0xeb, 0xe8, 0x07, 0x8e, 0x87, 0xd3, 0x57,
-			0xb2, 0x61, 0x12, 0xb8, 0x2a, 0x90, 0xb7, 0x2f},
-		[]byte{0xa3, 0xc2, 0x60, 0xb1, 0x0b, 0xb7, 0x28, 0x6e},
-		[]byte{
-			0x6b, 0xc1, 0xbe, 0xe2, 0x2e, 0x40, 0x9f, 0x96, 0xe9, 0x3d, 0x7e, 0x11, 0x73, 0x93, 0x17, 0x2a,
-			0xae, 0x2d, 0x8a, 0x57, 0x1e, 0x03, 0xac, 0x9c, 0x9e, 0xb7, 0x6f, 0xac, 0x45, 0xaf, 0x8e, 0x51,
-			0x30, 0xc8, 0x1c, 0x46, 0xa3, 0x5c, 0xe4, 0x11, 0xe5, 0xfb, 0xc1, 0x19, 0x1a, 0x0a, 0x52, 0xef,
-			0xf6, 0x9f, 0x24, 0x45, 0xdf, 0x4f, 0x9b, 0x17, 0xad, 0x2b, 0x41, 0x7b, 0xe6, 0x6c, 0x37, 0x10,
-		},
-	))
-	fmt.Printf(" AES CTR %x\n", aesCTR(
-		[]byte{0x2b, 0x7e, 0x15, 0x16, 0x28, 0xae, 0xd2, 0xa6, 0xab, 0xf7, 0x15, 0x88, 0x09, 0xcf, 0x4f, 0x3c},
-		[]byte{0xf0, 0xf1, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8, 0xf9, 0xfa, 0xfb, 0xfc, 0xfd, 0xfe, 0xff},
-		[]byte{
-			0x6b, 0xc1, 0xbe, 0xe2, 0x2e, 0x40, 0x9f, 0x96, 0xe9, 0x3d, 0x7e, 0x11, 0x73, 0x93, 0x17, 0x2a,
-			0xae, 0x2d, 0x8a, 0x57, 0x1e, 0x03, 0xac, 0x9c, 0x9e, 0xb7, 0x6f, 0xac, 0x45, 0xaf, 0x8e, 0x51,
-			0x30, 0xc8, 0x1c, 0x46, 0xa3, 0x5c, 0xe4, 0x11, 0xe5, 0xfb, 0xc1, 0x19, 0x1a, 0x0a, 0x52, 0xef,
-			0xf6, 0x9f, 0x24, 0x45, 0xdf, 0x4f, 0x9b, 0x17, 0xad, 0x2b, 0x41, 0x7b, 0xe6, 0x6c, 0x37, 0x10,
-		},
-	))
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
 
-func desCTR(key, iv, in []byte) []byte {
-	c, _ := des.NewCipher(key)
-	ctr := NewCTR(c, iv)
-	out := make([]byte, len(in))
-	ctr.XORKeyStream(out, in)
-	return out
+// run runs the command named by args[0] and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		for _, c := range commands {
+			if c.name != args[0] {
+				continue
+			}
+			err := c.run(args[1:], stdin, stdout, stderr)
+			switch {
+			case err == nil:
+				return 0
+			case errors.Is(err, errUsage):
+				return 2
+			default:
+				fmt.Fprintf(stderr, "go-crypto %s: %s\n", c.name, err)
+				return 1
+			}
+		}
+		if args[0] != "help" && args[0] != "-h" && args[0] != "-help" {
+			fmt.Fprintf(stderr, "go-crypto: unknown command %q\n", args[0])
+		}
+	}
+	fmt.Fprintf(stderr, "usage: go-crypto <command> [flags] [arguments]\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(stderr, "  %-10s %s\n", c.name, c.short)
+	}
+	fmt.Fprintf(stderr, "\nrun \"go-crypto <command> -h\" for the flags of a command\n")
+	return 2
 }
 
-func tripleDESCTR(key, iv, in []byte) []byte {
-	c, _ := des.NewTripleDESCipher(key)
-	ctr := NewCTR(c, iv)
-	out := make([]byte, len(in))
-	ctr.XORKeyStream(out, in)
-	return out
+func cryptCommand(name string, decrypt bool) func([]string, io.Reader, io.Writer, io.Writer) error {
+	return func(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+		inform, outform := "raw", "hex"
+		if decrypt {
+			inform, outform = outform, inform
+		}
+		fs := flag.NewFlagSet(name, flag.ContinueOnError)
+		fs.SetOutput(stderr)
+		fs.Usage = func() {
+			fmt.Fprintf(stderr, "usage: go-crypto %s -key hex -iv hex [flags] [file ...]\n", name)
+			fs.PrintDefaults()
+		}
+		alg := fs.String("alg", "aes", "block cipher: aes, des or 3des")
+		mode := fs.String("mode", "ctr", "mode of operation: ctr, cbc (with PKCS #7 padding), cfb or ofb")
+		keyHex := fs.String("key", "", "key in hex")
+		ivHex := fs.String("iv", "", "IV, or initial counter block for ctr, in hex")
+		pass := fs.String("pass", "", "derive the key from a password instead: pass:password, env:var or file:pathname")
+		kdf := fs.String("kdf", "scrypt", "key derivation for -pass: scrypt, pbkdf2 or argon2id")
+		fs.StringVar(&inform, "inform", inform, "input encoding: raw, hex or base64")
+		fs.StringVar(&outform, "outform", outform, "output encoding: raw, hex or base64")
+		if err := fs.Parse(args); err != nil {
+			return errUsage
+		}
+		for _, enc := range []string{inform, outform} {
+			if enc != "raw" && enc != "hex" && enc != "base64" {
+				return fmt.Errorf("unknown encoding %q", enc)
+			}
+		}
+		if *pass != "" {
+			if *keyHex != "" || *ivHex != "" {
+				return fmt.Errorf("-pass cannot be used with -key or -iv")
+			}
+			if *mode != "ctr" {
+				return fmt.Errorf("-pass requires mode ctr")
+			}
+			params := &PBEParams{KDF: *kdf}
+			switch *alg {
+			case "aes":
+				params.Cipher = "aes-256-ctr"
+			case "3des":
+				params.Cipher = "des-ede3-ctr"
+			default:
+				return fmt.Errorf("-pass requires alg aes or 3des")
+			}
+			return passwordCrypt(*pass, params, fs.Args(), stdin, stdout, inform, outform, decrypt)
+		}
+
+		key, err := hex.DecodeString(*keyHex)
+		if err != nil {
+			return fmt.Errorf("invalid key: %s", err)
+		}
+		block, err := newBlock(*alg, key)
+		if err != nil {
+			return err
+		}
+		iv, err := hex.DecodeString(*ivHex)
+		if err != nil {
+			return fmt.Errorf("invalid IV: %s", err)
+		}
+		if len(iv) != block.BlockSize() {
+			return fmt.Errorf("IV must be %d bytes for %s", block.BlockSize(), *alg)
+		}
+
+		in, err := readInputs(fs.Args(), stdin)
+		if err != nil {
+			return err
+		}
+		if in, err = decode(inform, in); err != nil {
+			return err
+		}
+		out, err := crypt(block, *mode, iv, in, decrypt)
+		if err != nil {
+			return err
+		}
+		return encode(stdout, outform, out)
+	}
 }
 
-func aesCTR(key, iv, in []byte) []byte {
-	c, _ := aes.NewCipher(key)
-	ctr := NewCTR(c, iv)
+// passwordCrypt encrypts or decrypts the named files with PasswordEncrypt
+// or PasswordDecrypt. The parameters of decryption come from the input.
+func passwordCrypt(pass string, params *PBEParams, names []string, stdin io.Reader, stdout io.Writer, inform, outform string, decrypt bool) error {
+	password, err := opensslPassword(pass)
+	if err != nil {
+		return err
+	}
+	in, err := readInputs(names, stdin)
+	if err != nil {
+		return err
+	}
+	if in, err = decode(inform, in); err != nil {
+		return err
+	}
+	var out []byte
+	if decrypt {
+		out, err = PasswordDecrypt(password, in)
+	} else {
+		out, err = PasswordEncrypt(password, in, params)
+	}
+	if err != nil {
+		return err
+	}
+	return encode(stdout, outform, out)
+}
+
+// newBlock returns the block cipher alg keyed with key.
+func newBlock(alg string, key []byte) (cipher.Block, error) {
+	switch alg {
+	case "aes":
+		return aes.NewCipher(key)
+	case "des":
+		return des.NewCipher(key)
+	case "3des":
+		return des.NewTripleDESCipher(key)
+	}
+	return nil, fmt.Errorf("unknown algorithm %q", alg)
+}
+
+// crypt encrypts or decrypts in with block in the given mode.
+func crypt(block cipher.Block, mode string, iv, in []byte, decrypt bool) ([]byte, error) {
+	var s cipher.Stream
+	switch mode {
+	case "ctr", "cfb", "ofb":
+		var err error
+		if _, s, err = registry.NewMode(block, mode, iv, decrypt); err != nil {
+			return nil, err
+		}
+	case "cbc":
+		bs := block.BlockSize()
+		if decrypt {
+			if len(in)%bs != 0 {
+				return nil, fmt.Errorf("input is not a multiple of the block size")
+			}
+			out := make([]byte, len(in))
+			cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, in)
+			return pkcs7Unpad(out, bs)
+		}
+		out := pkcs7Pad(append([]byte(nil), in...), bs)
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, out)
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unknown mode %q", mode)
+	}
 	out := make([]byte, len(in))
-	ctr.XORKeyStream(out, in)
-	return out
+	s.XORKeyStream(out, in)
+	return out, nil
+}
+
+// readInputs returns the concatenation of the named files, where "-" or no
+// names at all stands for stdin.
+func readInputs(names []string, stdin io.Reader) ([]byte, error) {
+	if len(names) == 0 {
+		return io.ReadAll(stdin)
+	}
+	var buf bytes.Buffer
+	for _, name := range names {
+		if name == "-" {
+			if _, err := buf.ReadFrom(stdin); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+	}
+	return buf.Bytes(), nil
+}
+
+// decode decodes in from the given encoding. White space is ignored in hex
+// and base64 input.
+func decode(encoding string, in []byte) ([]byte, error) {
+	switch encoding {
+	case "raw":
+		return in, nil
+	case "hex":
+		b, err := hex.DecodeString(string(bytes.Join(bytes.Fields(in), nil)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex input: %s", err)
+		}
+		return b, nil
+	case "base64":
+		b, err := base64.StdEncoding.DecodeString(string(bytes.Join(bytes.Fields(in), nil)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 input: %s", err)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("unknown encoding %q", encoding)
+}
+
+// encode writes out to w in the given encoding, followed by a newline
+// unless it is raw.
+func encode(w io.Writer, encoding string, out []byte) error {
+	var err error
+	switch encoding {
+	case "raw":
+		_, err = w.Write(out)
+	case "hex":
+		_, err = fmt.Fprintf(w, "%x\n", out)
+	case "base64":
+		_, err = fmt.Fprintln(w, base64.StdEncoding.EncodeToString(out))
+	default:
+		err = fmt.Errorf("unknown encoding %q", encoding)
+	}
+	return err
 }
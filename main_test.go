@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runCommand(stdin []byte, args ...string) (stdout, stderr string, code int) {
+	var out, errOut bytes.Buffer
+	code = run(args, bytes.NewReader(stdin), &out, &errOut)
+	return out.String(), errOut.String(), code
+}
+
+var cliPlaintext = "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710"
+
+// The outputs the command printed before it took flags.
+var cliTests = []struct {
+	alg, key, iv, out string
+}{
+	{
+		"des", "6e5ee247c4bff651", "a3c260b10bb7286e",
+		"7d2ed15ba0ff5c7f9d0a027bf66413f1ad0dd612e9a3731f712930f200835097caa0aa136bcee3f640f980641c0df56ac98be8924c1dc63151829068fa9d737e",
+	},
+	{
+		"3des", "cb107dda7e96570ae8ebe8078e87d357b26112b82a90b72f", "a3c260b10bb7286e",
+		"3db2c3199be15c485d3812e95a9b315b48567349a7a0a313a5995bf2279316b79980fa6c3a624d54f54ea29a16c8114c06072aab63bbdfccec595031192e9b7c",
+	},
+	{
+		"aes", "2b7e151628aed2a6abf7158809cf4f3c", "f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+		"874d6191b620e3261bef6864990db6ce9806f66b7970fdff8617187bb9fffdff5ae4df3edbd5d35e5b4f09020db03eab1e031dda2fbe03d1792170a0f3009cee",
+	},
+}
+
+func Test_run_encrypt(t *testing.T) {
+	for _, tt := range cliTests {
+		out, errOut, code := runCommand([]byte(cliPlaintext), "encrypt", "-alg", tt.alg, "-key", tt.key, "-iv", tt.iv, "-inform", "hex")
+		if code != 0 || out != tt.out+"\n" {
+			t.Errorf("%s: encrypt = %q, %q, %d, want %q", tt.alg, out, errOut, code, tt.out)
+		}
+		out, errOut, code = runCommand([]byte(tt.out+"\n"), "decrypt", "-alg", tt.alg, "-key", tt.key, "-iv", tt.iv, "-outform", "hex")
+		if code != 0 || out != cliPlaintext+"\n" {
+			t.Errorf("%s: decrypt = %q, %q, %d, want %q", tt.alg, out, errOut, code, cliPlaintext)
+		}
+	}
+}
+
+func Test_run_RoundTrip(t *testing.T) {
+	plaintext := []byte("The quick brown fox jumps over the lazy dog")
+	for _, tt := range cliTests {
+		for _, mode := range []string{"ctr", "cbc", "cfb", "ofb"} {
+			for _, enc := range []string{"raw", "hex", "base64"} {
+				flags := []string{"-alg", tt.alg, "-mode", mode, "-key", tt.key, "-iv", tt.iv}
+				ct, errOut, code := runCommand(plaintext, append([]string{"encrypt", "-outform", enc}, flags...)...)
+				if code != 0 {
+					t.Fatalf("%s/%s/%s: encrypt = %q, %d", tt.alg, mode, enc, errOut, code)
+				}
+				pt, errOut, code := runCommand([]byte(ct), append([]string{"decrypt", "-inform", enc, "-outform", "raw"}, flags...)...)
+				if code != 0 || pt != string(plaintext) {
+					t.Errorf("%s/%s/%s: decrypt = %q, %q, %d", tt.alg, mode, enc, pt, errOut, code)
+				}
+			}
+		}
+	}
+}
+
+func Test_run_Files(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	in, _ := hex.DecodeString(cliPlaintext)
+	os.WriteFile(a, in[:20], 0o600)
+	os.WriteFile(b, in[40:], 0o600)
+	tt := cliTests[2]
+	out, errOut, code := runCommand(in[20:40], "encrypt", "-key", tt.key, "-iv", tt.iv, a, "-", b)
+	if code != 0 || out != tt.out+"\n" {
+		t.Errorf("encrypt files = %q, %q, %d, want %q", out, errOut, code, tt.out)
+	}
+	if _, _, code := runCommand(nil, "encrypt", "-key", tt.key, "-iv", tt.iv, filepath.Join(dir, "missing")); code != 1 {
+		t.Errorf("encrypt of a missing file exited with %d, want 1", code)
+	}
+}
+
+func Test_run_Errors(t *testing.T) {
+	tt := cliTests[2]
+	tests := []struct {
+		args []string
+		code int
+		msg  string
+	}{
+		{nil, 2, "usage"},
+		{[]string{"frobnicate"}, 2, "unknown command"},
+		{[]string{"encrypt", "-nosuchflag"}, 2, "not defined"},
+		{[]string{"encrypt", "-alg", "rc4", "-key", tt.key, "-iv", tt.iv}, 1, "unknown algorithm"},
+		{[]string{"encrypt", "-mode", "xts", "-key", tt.key, "-iv", tt.iv}, 1, "unknown mode"},
+		{[]string{"encrypt", "-outform", "pem", "-key", tt.key, "-iv", tt.iv}, 1, "unknown encoding"},
+		{[]string{"encrypt", "-key", "zz", "-iv", tt.iv}, 1, "invalid key"},
+		{[]string{"encrypt", "-key", tt.key[:30], "-iv", tt.iv}, 1, "invalid key size"},
+		{[]string{"encrypt", "-key", tt.key, "-iv", tt.iv[:16]}, 1, "IV must be 16 bytes"},
+		{[]string{"decrypt", "-key", tt.key, "-iv", tt.iv}, 1, "invalid hex input"},
+		{[]string{"decrypt", "-mode", "cbc", "-inform", "raw", "-key", tt.key, "-iv", tt.iv}, 1, "multiple of the block size"},
+	}
+	for _, test := range tests {
+		_, errOut, code := runCommand([]byte("not hex"), test.args...)
+		if code != test.code || !strings.Contains(errOut, test.msg) {
+			t.Errorf("%q = %q, %d, want %q, %d", test.args, errOut, code, test.msg, test.code)
+		}
+	}
+
+	// A wrong key makes the CBC padding check fail.
+	ct, _, _ := runCommand([]byte("secret"), "encrypt", "-mode", "cbc", "-key", tt.key, "-iv", tt.iv)
+	if _, errOut, code := runCommand([]byte(ct), "decrypt", "-mode", "cbc", "-key", strings.Repeat("00", 16), "-iv", tt.iv); code != 1 || !strings.Contains(errOut, "invalid padding") {
+		t.Errorf("decrypt with a wrong key = %q, %d", errOut, code)
+	}
+}
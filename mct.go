@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/AirWSW/go-crypto/registry"
+)
+
+// mctIterations returns the number of inner iterations of a Monte Carlo
+// test: 1000 for AES (AESAVS section 6.4) and 10000 for TDES (TMOVS).
+func (s cavpSuite) mctIterations() int {
+	if s.alg == "aes" {
+		return 1000
+	}
+	return 10000
+}
+
+// monteCarlo runs the inner loop of the Monte Carlo test v and returns the
+// last output and the inputs of the next outer iteration. Each record of a
+// Monte Carlo test file holds the inputs and the last output of one outer
+// iteration.
+//
+// The inner loop feeds the outputs back as inputs: in ECB mode each output
+// is the next input, in the other modes the inputs are the IV, one segment
+// at a time, and then the outputs from one block earlier. For CFB8 a
+// segment is a byte, otherwise a block. The key of the next outer iteration
+// is the key XORed with the last outputs, its IV the last output block, and
+// its input the one that would follow in the inner loop.
+func (s cavpSuite) monteCarlo(v *cavpVector) ([]byte, *cavpVector, error) {
+	block, err := newBlock(s.alg, v.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	bs := block.BlockSize()
+	seg := bs
+	if s.mode == "cfb8" {
+		seg = 1
+	}
+	if s.mode != "ecb" && len(v.iv) != bs {
+		return nil, nil, fmt.Errorf("invalid IV length")
+	}
+	if len(v.in) != seg {
+		return nil, nil, fmt.Errorf("invalid input length")
+	}
+	if s.mode == "ctr" {
+		return nil, nil, fmt.Errorf("no Monte Carlo test for CTR mode")
+	}
+	bm, stream, err := registry.NewMode(block, s.mode, v.iv, v.decrypt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	n := s.mctIterations()
+	out := make([]byte, n*seg)
+	in := v.in
+	for j := 0; j < n; j++ {
+		o := out[j*seg : (j+1)*seg]
+		if bm != nil {
+			bm.CryptBlocks(o, in)
+		} else {
+			stream.XORKeyStream(o, in)
+		}
+		switch {
+		case s.mode == "ecb":
+			in = o
+		case j < bs/seg:
+			in = v.iv[j*seg : (j+1)*seg]
+		default:
+			in = out[(j-bs/seg)*seg : (j-bs/seg+1)*seg]
+		}
+	}
+
+	next := &cavpVector{
+		decrypt: v.decrypt,
+		key:     s.mctKey(v.key, out),
+		in:      append([]byte(nil), in...),
+	}
+	if s.mode != "ecb" {
+		next.iv = append([]byte(nil), out[len(out)-bs:]...)
+	}
+	return out[len(out)-seg:], next, nil
+}
+
+// mctKey returns the key of the next outer iteration. An AES key is XORed
+// with as many of the last output bytes. Each TDES key is XORed with one of
+// the last three output blocks, the last one going to the first key, and
+// set to odd parity; with two keys the third stays equal to the first, and
+// with one key all three stay equal.
+func (s cavpSuite) mctKey(key, out []byte) []byte {
+	next := make([]byte, len(key))
+	if s.alg == "aes" {
+		xorBytes(next, key, out[len(out)-len(key):])
+		return next
+	}
+	k1, k2, k3 := key[:8], key[8:16], key[16:]
+	tail := out[len(out)-24:]
+	xorBytes(next[:8], k1, tail[16:])
+	switch {
+	case bytes.Equal(k1, k2) && bytes.Equal(k1, k3):
+		copy(next[8:], next[:8])
+		copy(next[16:], next[:8])
+	case bytes.Equal(k1, k3):
+		xorBytes(next[8:16], k2, tail[8:16])
+		copy(next[16:], next[:8])
+	default:
+		xorBytes(next[8:16], k2, tail[8:16])
+		xorBytes(next[16:], k3, tail[:8])
+	}
+	for i, b := range next {
+		next[i] = oddParity(b)
+	}
+	return next
+}
+
+// oddParity returns b with its least significant bit set so that it has an
+// odd number of bits set, as in a DES key byte.
+func oddParity(b byte) byte {
+	b &^= 1
+	p := b ^ b>>4
+	p ^= p >> 2
+	p ^= p >> 1
+	return b | ^p&1
+}
+
+// sameInputs reports whether v and w have the same key, IV and input.
+func (v *cavpVector) sameInputs(w *cavpVector) bool {
+	return bytes.Equal(v.key, w.key) && bytes.Equal(v.iv, w.iv) && bytes.Equal(v.in, w.in)
+}
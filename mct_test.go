@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The first Monte Carlo tests of ECBMCT128.rsp and CBCMCT128.rsp in AESAVS.
+var mctTests = []struct {
+	mode string
+	v    cavpVector
+}{
+	{
+		"ecb",
+		cavpVector{
+			key:  []byte{0x13, 0x9a, 0x35, 0x42, 0x2f, 0x1d, 0x61, 0xde, 0x3c, 0x91, 0x78, 0x7f, 0xe0, 0x50, 0x7a, 0xfd},
+			in:   []byte{0xb9, 0x14, 0x5a, 0x76, 0x8b, 0x7d, 0xc4, 0x89, 0xa0, 0x96, 0xb5, 0x46, 0xf4, 0x3b, 0x23, 0x1f},
+			want: []byte{0xd7, 0xc3, 0xff, 0xac, 0x90, 0x31, 0x23, 0x86, 0x50, 0x90, 0x1e, 0x15, 0x73, 0x64, 0xc3, 0x86},
+		},
+	},
+	{
+		"cbc",
+		cavpVector{
+			key:  []byte{0x9d, 0xc2, 0xc8, 0x4a, 0x37, 0x85, 0x0c, 0x11, 0x69, 0x98, 0x18, 0x60, 0x5f, 0x47, 0x95, 0x8c},
+			iv:   []byte{0x25, 0x69, 0x53, 0xb2, 0xfe, 0xab, 0x2a, 0x04, 0xae, 0x01, 0x80, 0xd8, 0x33, 0x5b, 0xbe, 0xd6},
+			in:   []byte{0x2e, 0x58, 0x66, 0x92, 0xe6, 0x47, 0xf5, 0x02, 0x8e, 0xc6, 0xfa, 0x47, 0xa5, 0x5a, 0x2a, 0xab},
+			want: []byte{0x1b, 0x1e, 0xbd, 0x1f, 0xc4, 0x5e, 0xc4, 0x30, 0x37, 0xfd, 0x48, 0x44, 0x24, 0x1a, 0x43, 0x7f},
+		},
+	},
+}
+
+func Test_cavpSuite_monteCarlo(t *testing.T) {
+	for _, tt := range mctTests {
+		s := cavpSuite{alg: "aes", mode: tt.mode, mct: true}
+		got, next, err := s.monteCarlo(&tt.v)
+		if err != nil || !bytes.Equal(got, tt.v.want) {
+			t.Errorf("%s: monteCarlo() = %x, %v, want %x", tt.mode, got, err, tt.v.want)
+			continue
+		}
+		// The key of the next iteration is XORed with the last output.
+		key := make([]byte, 16)
+		xorBytes(key, tt.v.key, got)
+		if !bytes.Equal(next.key, key) {
+			t.Errorf("%s: next key = %x, want %x", tt.mode, next.key, key)
+		}
+		// In ECB mode decrypting the output as many times gives back the
+		// input.
+		if tt.mode == "ecb" {
+			d := &cavpVector{decrypt: true, key: tt.v.key, in: got}
+			if back, _, err := s.monteCarlo(d); err != nil || !bytes.Equal(back, tt.v.in) {
+				t.Errorf("%s: decrypting monteCarlo() = %x, %v, want %x", tt.mode, back, err, tt.v.in)
+			}
+		}
+	}
+}
+
+func Test_cavpSuite_mctKey(t *testing.T) {
+	s := cavpSuite{alg: "3des"}
+	k1 := []byte{0x01, 0x02, 0x04, 0x07, 0x08, 0x0b, 0x0d, 0x0e}
+	k2 := []byte{0x10, 0x13, 0x15, 0x16, 0x19, 0x1a, 0x1c, 0x1f}
+	k3 := []byte{0x20, 0x23, 0x25, 0x26, 0x29, 0x2a, 0x2c, 0x2f}
+	out := make([]byte, 32)
+	for i := range out {
+		out[i] = byte(i) << 1
+	}
+	join := func(k ...[]byte) []byte { return bytes.Join(k, nil) }
+	parity := func(k []byte) []byte {
+		for i := range k {
+			k[i] = oddParity(k[i])
+		}
+		return k
+	}
+	xor := func(a, b []byte) []byte {
+		c := make([]byte, len(a))
+		xorBytes(c, a, b)
+		return parity(c)
+	}
+	n1, n2, n3 := xor(k1, out[24:]), xor(k2, out[16:24]), xor(k3, out[8:16])
+	tests := []struct{ key, want []byte }{
+		{join(k1, k2, k3), join(n1, n2, n3)},
+		{join(k1, k2, k1), join(n1, n2, n1)},
+		{join(k1, k1, k1), join(n1, n1, n1)},
+	}
+	for i, tt := range tests {
+		if got := s.mctKey(tt.key, out); !bytes.Equal(got, tt.want) {
+			t.Errorf("#%d: mctKey() = %x, want %x", i, got, tt.want)
+		}
+	}
+
+	for b := 0; b < 256; b++ {
+		p := oddParity(byte(b))
+		ones := 0
+		for x := p; x != 0; x &= x - 1 {
+			ones++
+		}
+		if p&^1 != byte(b)&^1 || ones%2 != 1 {
+			t.Errorf("oddParity(%#02x) = %#02x", b, p)
+		}
+	}
+}
+
+func Test_cavpCommand_MonteCarlo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Monte Carlo tests take a few seconds")
+	}
+	files, err := filepath.Glob(filepath.Join("testdata", "modes", "*M[CO][TN]*.rsp"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no Monte Carlo response files: %v", err)
+	}
+	out, errOut, code := runCommand(nil, append([]string{"cavp", "-q"}, files...)...)
+	if code != 0 || strings.Contains(out, "FAIL") || strings.Contains(out, "SKIP") {
+		t.Errorf("cavp exited with %d: %s%s", code, out, errOut)
+	}
+}
+
+func Test_cavpCommand_MonteCarloChain(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "modes", "ECBMCT128.rsp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keep the first two tests and change the key of the second, then fix
+	// its expected output so that only the chaining is wrong.
+	s := string(b)
+	s = s[:strings.Index(s, "COUNT = 2")]
+	s = strings.Replace(s, "KEY = c459caeebf2c42586c01666a9334b97b", "KEY = 00000000000000000000000000000000", 1)
+	s = strings.Replace(s, "CIPHERTEXT = bc3637da2daf8fcf7c68bb28c143a0a4", "CIPHERTEXT = 00", 1)
+	bad := filepath.Join(t.TempDir(), "ECBMCT128.rsp")
+	os.WriteFile(bad, []byte(s), 0o600)
+
+	out, _, code := runCommand(nil, "cavp", bad)
+	if code != 1 || !strings.Contains(out, "COUNT = 0: PASS") || !strings.Contains(out, "COUNT = 1: FAIL line 14: inputs do not follow from the previous vector") {
+		t.Errorf("cavp exited with %d:\n%s", code, out)
+	}
+}
@@ -0,0 +1,270 @@
+// OpenSSL enc: symmetric cipher routines
+// https://docs.openssl.org/3.0/man1/openssl-enc/
+//
+// OpenSSL EVP_BytesToKey
+// https://docs.openssl.org/3.0/man3/EVP_BytesToKey/
+
+package main
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/AirWSW/go-crypto/registry"
+)
+
+const (
+	opensslMagic    = "Salted__"
+	opensslSaltSize = 8
+
+	// OpenSSLIter is the default number of PBKDF2 iterations of openssl enc.
+	OpenSSLIter = 10000
+)
+
+// OpenSSLOptions are the options of openssl enc that select how the key and
+// IV are derived from the password.
+type OpenSSLOptions struct {
+	// Cipher is the name of a registered cipher, such as "aes-256-ctr".
+	Cipher string
+
+	// Hash is the digest used by the key derivation, the -md option. If it
+	// is nil, SHA-256 is used, the default since OpenSSL 1.1.0.
+	Hash func() hash.Hash
+
+	// PBKDF2 selects PBKDF2 with Iter iterations, or OpenSSLIter if Iter
+	// is 0, instead of EVP_BytesToKey with a single iteration.
+	PBKDF2 bool
+	Iter   int
+
+	// NoSalt leaves out the salt and the header that holds it.
+	NoSalt bool
+
+	// Salt is the 8-byte salt to encrypt with. If it is nil, a random salt
+	// is used.
+	Salt []byte
+}
+
+// OpenSSLHashes are the digests accepted for the -md option, by name.
+var OpenSSLHashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// OpenSSLEncrypt encrypts plaintext with a key and IV derived from password
+// as openssl enc does. Unless opts.NoSalt is set the output starts with
+// "Salted__" and the salt. ECB and CBC modes pad with PKCS #7.
+func OpenSSLEncrypt(password, plaintext []byte, opts *OpenSSLOptions) ([]byte, error) {
+	var salt []byte
+	if !opts.NoSalt {
+		salt = opts.Salt
+		if salt == nil {
+			salt = make([]byte, opensslSaltSize)
+			if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+				return nil, err
+			}
+		}
+		if len(salt) != opensslSaltSize {
+			return nil, fmt.Errorf("salt must be %d bytes", opensslSaltSize)
+		}
+	}
+	out, err := opensslCrypt(password, salt, plaintext, opts, false)
+	if err != nil || salt == nil {
+		return out, err
+	}
+	return append(append([]byte(opensslMagic), salt...), out...), nil
+}
+
+// OpenSSLDecrypt decrypts the output of openssl enc, or of OpenSSLEncrypt,
+// with a key and IV derived from password.
+func OpenSSLDecrypt(password, data []byte, opts *OpenSSLOptions) ([]byte, error) {
+	var salt []byte
+	if !opts.NoSalt {
+		n := len(opensslMagic) + opensslSaltSize
+		if len(data) < n || string(data[:len(opensslMagic)]) != opensslMagic {
+			return nil, fmt.Errorf("bad magic number")
+		}
+		salt, data = data[len(opensslMagic):n], data[n:]
+	}
+	return opensslCrypt(password, salt, data, opts, true)
+}
+
+func opensslCrypt(password, salt, in []byte, opts *OpenSSLOptions, decrypt bool) ([]byte, error) {
+	c, err := registry.LookupCipher(opts.Cipher)
+	if err != nil {
+		return nil, err
+	}
+	h := opts.Hash
+	if h == nil {
+		h = sha256.New
+	}
+	var key, iv []byte
+	if opts.PBKDF2 {
+		iter := opts.Iter
+		if iter == 0 {
+			iter = OpenSSLIter
+		}
+		dk := pbkdf2Key(h, password, salt, iter, c.KeySize+c.IVSize)
+		key, iv = dk[:c.KeySize], dk[c.KeySize:]
+	} else {
+		key, iv = evpBytesToKey(h, password, salt, c.KeySize, c.IVSize)
+	}
+
+	if c.NewStream != nil {
+		s, err := c.NewStream(key, iv, decrypt)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(in))
+		s.XORKeyStream(out, in)
+		return out, nil
+	}
+	bm, err := c.NewBlockMode(key, iv, decrypt)
+	if err != nil {
+		return nil, err
+	}
+	bs := bm.BlockSize()
+	if decrypt {
+		if len(in)%bs != 0 {
+			return nil, fmt.Errorf("input is not a multiple of the block size")
+		}
+		out := make([]byte, len(in))
+		bm.CryptBlocks(out, in)
+		return pkcs7Unpad(out, bs)
+	}
+	out := pkcs7Pad(append([]byte(nil), in...), bs)
+	bm.CryptBlocks(out, out)
+	return out, nil
+}
+
+// evpBytesToKey derives a key and IV from password and salt as
+// EVP_BytesToKey does with a single iteration: D_i = H(D_i-1 || password ||
+// salt), concatenated until there are enough bytes. It is the key derivation
+// of openssl enc without -pbkdf2.
+func evpBytesToKey(h func() hash.Hash, password, salt []byte, keyLen, ivLen int) (key, iv []byte) {
+	var d, prev []byte
+	md := h()
+	for len(d) < keyLen+ivLen {
+		md.Reset()
+		md.Write(prev)
+		md.Write(password)
+		md.Write(salt)
+		prev = md.Sum(nil)
+		d = append(d, prev...)
+	}
+	return d[:keyLen], d[keyLen : keyLen+ivLen]
+}
+
+func opensslCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("enc", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: go-crypto enc [-d] -cipher name -pass arg [flags] [file ...]\n")
+		fs.PrintDefaults()
+	}
+	decrypt := fs.Bool("d", false, "decrypt")
+	var opts OpenSSLOptions
+	fs.StringVar(&opts.Cipher, "cipher", "", "cipher name, as listed by the ciphers command")
+	pass := fs.String("pass", "", "password source: pass:password, env:var or file:pathname")
+	md := fs.String("md", "sha256", "digest of the key derivation: md5, sha1, sha256 or sha512")
+	fs.BoolVar(&opts.PBKDF2, "pbkdf2", false, "derive the key and IV with PBKDF2")
+	fs.IntVar(&opts.Iter, "iter", 0, "number of PBKDF2 iterations (implies -pbkdf2)")
+	fs.BoolVar(&opts.NoSalt, "nosalt", false, "do not use a salt")
+	saltHex := fs.String("S", "", "salt in hex, instead of a random one")
+	b64 := fs.Bool("a", false, "base64 encode the ciphertext")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if opts.Iter > 0 {
+		opts.PBKDF2 = true
+	}
+	var ok bool
+	if opts.Hash, ok = OpenSSLHashes[*md]; !ok {
+		return fmt.Errorf("unknown digest %q", *md)
+	}
+	if *saltHex != "" {
+		var err error
+		if opts.Salt, err = decode("hex", []byte(*saltHex)); err != nil {
+			return fmt.Errorf("invalid salt: %s", err)
+		}
+	}
+	password, err := opensslPassword(*pass)
+	if err != nil {
+		return err
+	}
+
+	in, err := readInputs(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	if *decrypt {
+		if *b64 {
+			if in, err = decode("base64", in); err != nil {
+				return err
+			}
+		}
+		out, err := OpenSSLDecrypt(password, in, &opts)
+		if err != nil {
+			return err
+		}
+		_, err = stdout.Write(out)
+		return err
+	}
+	out, err := OpenSSLEncrypt(password, in, &opts)
+	if err != nil {
+		return err
+	}
+	if !*b64 {
+		_, err = stdout.Write(out)
+		return err
+	}
+	// openssl enc -a writes lines of 64 characters.
+	s := base64.StdEncoding.EncodeToString(out)
+	for len(s) > 64 {
+		fmt.Fprintln(stdout, s[:64])
+		s = s[64:]
+	}
+	_, err = fmt.Fprintln(stdout, s)
+	return err
+}
+
+// opensslPassword returns the password given by arg in the syntax of the
+// -pass option of openssl: pass:password, env:var or file:pathname, of which
+// the first line is the password.
+func opensslPassword(arg string) ([]byte, error) {
+	i := strings.IndexByte(arg, ':')
+	if i < 0 {
+		return nil, fmt.Errorf("-pass must be pass:password, env:var or file:pathname")
+	}
+	switch v := arg[i+1:]; arg[:i] {
+	case "pass":
+		return []byte(v), nil
+	case "env":
+		p, ok := os.LookupEnv(v)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", v)
+		}
+		return []byte(p), nil
+	case "file":
+		b, err := os.ReadFile(v)
+		if err != nil {
+			return nil, err
+		}
+		if i := strings.IndexAny(string(b), "\r\n"); i >= 0 {
+			b = b[:i]
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("unknown password source %q", arg[:i])
+}
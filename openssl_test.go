@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The files in testdata/openssl were written by OpenSSL 3.0 from plain.txt,
+// or an empty file, with -pass pass:correct-horse and the other options of
+// each test. The DES ones need -provider legacy -provider default.
+var opensslTests = []struct {
+	file  string
+	opts  OpenSSLOptions
+	empty bool
+}{
+	{file: "aes-256-ctr.pbkdf2.bin", opts: OpenSSLOptions{Cipher: "aes-256-ctr", PBKDF2: true}},
+	{file: "aes-128-cbc.md5.bin", opts: OpenSSLOptions{Cipher: "aes-128-cbc", Hash: md5.New}},
+	{file: "aes-192-cfb.sha256.bin", opts: OpenSSLOptions{Cipher: "aes-192-cfb"}},
+	{file: "aes-128-cfb8.pbkdf2-sha512-1000.bin", opts: OpenSSLOptions{Cipher: "aes-128-cfb8", Hash: sha512.New, PBKDF2: true, Iter: 1000}},
+	{file: "aes-256-ofb.pbkdf2-sha1.bin", opts: OpenSSLOptions{Cipher: "aes-256-ofb", Hash: sha1.New, PBKDF2: true}},
+	{file: "aes-256-ecb.nosalt.bin", opts: OpenSSLOptions{Cipher: "aes-256-ecb", NoSalt: true}},
+	{file: "des-ede3-cbc.pbkdf2-2000.bin", opts: OpenSSLOptions{Cipher: "des-ede3-cbc", PBKDF2: true, Iter: 2000}},
+	{file: "des-ede-cfb.md5.bin", opts: OpenSSLOptions{Cipher: "des-ede-cfb", Hash: md5.New}},
+	{file: "des-cbc.pbkdf2.bin", opts: OpenSSLOptions{Cipher: "des-cbc", PBKDF2: true}},
+	{file: "des-ofb.md5.bin", opts: OpenSSLOptions{Cipher: "des-ofb", Hash: md5.New}},
+	{file: "aes-128-cbc.pbkdf2.empty.bin", opts: OpenSSLOptions{Cipher: "aes-128-cbc", PBKDF2: true}, empty: true},
+}
+
+var opensslTestPassword = []byte("correct-horse")
+
+func Test_OpenSSLDecrypt(t *testing.T) {
+	plain, err := os.ReadFile(filepath.Join("testdata", "openssl", "plain.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range opensslTests {
+		data, err := os.ReadFile(filepath.Join("testdata", "openssl", tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := plain
+		if tt.empty {
+			want = nil
+		}
+		got, err := OpenSSLDecrypt(opensslTestPassword, data, &tt.opts)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%s: OpenSSLDecrypt() = %q, %v, want %q", tt.file, got, err, want)
+		}
+
+		// Encrypting again under the same salt gives the same file.
+		opts := tt.opts
+		if !opts.NoSalt {
+			opts.Salt = data[8:16]
+		}
+		if got, err := OpenSSLEncrypt(opensslTestPassword, want, &opts); err != nil || !bytes.Equal(got, data) {
+			t.Errorf("%s: OpenSSLEncrypt() = %x, %v, want %x", tt.file, got, err, data)
+		}
+	}
+}
+
+func Test_OpenSSLDecrypt_errors(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "openssl", "aes-128-cbc.md5.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &OpenSSLOptions{Cipher: "aes-128-cbc", Hash: md5.New}
+	if _, err := OpenSSLDecrypt([]byte("battery-staple"), data, opts); err == nil {
+		t.Errorf("OpenSSLDecrypt() with the wrong password succeeded")
+	}
+	if _, err := OpenSSLDecrypt(opensslTestPassword, data[8:], opts); err == nil || err.Error() != "bad magic number" {
+		t.Errorf("OpenSSLDecrypt() without the header = %v, want bad magic number", err)
+	}
+	if _, err := OpenSSLDecrypt(opensslTestPassword, data[:len(data)-1], opts); err == nil {
+		t.Errorf("OpenSSLDecrypt() of a truncated file succeeded")
+	}
+	if _, err := OpenSSLEncrypt(opensslTestPassword, nil, &OpenSSLOptions{Cipher: "aes-128-cbc", Salt: []byte("short")}); err == nil {
+		t.Errorf("OpenSSLEncrypt() with a 5-byte salt succeeded")
+	}
+}
+
+func Test_run_enc(t *testing.T) {
+	plain, err := os.ReadFile(filepath.Join("testdata", "openssl", "plain.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join("testdata", "openssl", "aes-256-cbc.pbkdf2.b64")
+	out, errOut, code := runCommand(nil, "enc", "-d", "-a", "-cipher", "aes-256-cbc", "-pbkdf2", "-pass", "pass:correct-horse", file)
+	if code != 0 || out != string(plain) {
+		t.Errorf("enc -d -a = %q, %q, %d, want %q", out, errOut, code, plain)
+	}
+	b64, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := decode("base64", b64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	salt := hex.EncodeToString(data[8:16])
+	out, errOut, code = runCommand(plain, "enc", "-a", "-cipher", "aes-256-cbc", "-pbkdf2", "-S", salt, "-pass", "pass:correct-horse")
+	if code != 0 || out != string(b64) {
+		t.Errorf("enc -a -S %s = %q, %q, %d, want %q", salt, out, errOut, code, b64)
+	}
+
+	t.Setenv("GO_CRYPTO_TEST_PASS", "correct-horse")
+	ct, errOut, code := runCommand(plain, "enc", "-cipher", "des-ede3-ctr", "-iter", "10", "-pass", "env:GO_CRYPTO_TEST_PASS")
+	if code != 0 || !strings.HasPrefix(ct, "Salted__") {
+		t.Fatalf("enc = %q, %q, %d", ct, errOut, code)
+	}
+	passFile := filepath.Join(t.TempDir(), "pass")
+	os.WriteFile(passFile, []byte("correct-horse\nignored\n"), 0o600)
+	out, errOut, code = runCommand([]byte(ct), "enc", "-d", "-cipher", "des-ede3-ctr", "-iter", "10", "-pass", "file:"+passFile)
+	if code != 0 || out != string(plain) {
+		t.Errorf("enc -d = %q, %q, %d, want %q", out, errOut, code, plain)
+	}
+
+	for _, args := range [][]string{
+		{"enc", "-cipher", "aes-128-ctr", "-pass", "correct-horse"},
+		{"enc", "-cipher", "rot13", "-pass", "pass:x"},
+		{"enc", "-cipher", "aes-128-ctr", "-md", "md4", "-pass", "pass:x"},
+	} {
+		if _, _, code := runCommand(nil, args...); code != 1 {
+			t.Errorf("%s: exit status %d, want 1", strings.Join(args, " "), code)
+		}
+	}
+}
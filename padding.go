@@ -0,0 +1,40 @@
+// RFC 5652: Cryptographic Message Syntax (CMS), Section 6.3 Content-encryption Process
+// https://www.rfc-editor.org/rfc/rfc5652#section-6.3
+
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+)
+
+var errPadding = fmt.Errorf("invalid padding")
+
+// pkcs7Pad appends PKCS #7 padding to b, between 1 and blockSize bytes each
+// holding the number of bytes added.
+func pkcs7Pad(b []byte, blockSize int) []byte {
+	n := blockSize - len(b)%blockSize
+	for i := 0; i < n; i++ {
+		b = append(b, byte(n))
+	}
+	return b
+}
+
+// pkcs7Unpad returns b without its PKCS #7 padding. It looks at the whole
+// last block whatever the padding length, so the time it takes does not
+// depend on the padding.
+func pkcs7Unpad(b []byte, blockSize int) ([]byte, error) {
+	if len(b) == 0 || len(b)%blockSize != 0 {
+		return nil, errPadding
+	}
+	n := int(b[len(b)-1])
+	good := subtle.ConstantTimeLessOrEq(1, n) & subtle.ConstantTimeLessOrEq(n, blockSize)
+	for i := 1; i <= blockSize; i++ {
+		pad := subtle.ConstantTimeLessOrEq(i, n)
+		good &= subtle.ConstantTimeByteEq(b[len(b)-i], byte(n)) | (pad ^ 1)
+	}
+	if good != 1 {
+		return nil, errPadding
+	}
+	return b[:len(b)-n], nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_pkcs7Pad(t *testing.T) {
+	for _, bs := range []int{8, 16} {
+		for n := 0; n <= 2*bs; n++ {
+			padded := pkcs7Pad(append([]byte(nil), commonInput[:n]...), bs)
+			p := bs - n%bs
+			if len(padded) != n+p || !bytes.Equal(padded[n:], bytes.Repeat([]byte{byte(p)}, p)) {
+				t.Errorf("%d/%d: pkcs7Pad() = %x", bs, n, padded)
+			}
+			got, err := pkcs7Unpad(padded, bs)
+			if err != nil || !bytes.Equal(got, commonInput[:n]) {
+				t.Errorf("%d/%d: pkcs7Unpad() = %x, %v, want %x", bs, n, got, err, commonInput[:n])
+			}
+		}
+	}
+}
+
+func Test_pkcs7Unpad(t *testing.T) {
+	tests := [][]byte{
+		{},
+		{1, 2, 3, 4, 5, 6, 7},
+		{1, 2, 3, 4, 5, 6, 7, 0},
+		{1, 2, 3, 4, 5, 6, 7, 9},
+		{1, 2, 3, 4, 5, 6, 2, 3},
+		{1, 2, 3, 4, 5, 6, 3, 3},
+		{7, 8, 8, 8, 8, 8, 8, 8},
+	}
+	for i, b := range tests {
+		if got, err := pkcs7Unpad(b, 8); err == nil {
+			t.Errorf("#%d: pkcs7Unpad(%x) = %x, want error", i, b, got)
+		}
+	}
+}
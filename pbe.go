@@ -0,0 +1,301 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/AirWSW/go-crypto/registry"
+)
+
+// Password-based encryption derives the key and IV of a CTR mode cipher and
+// an HMAC-SHA256 key from a password and a random salt. The output is
+//
+//	header || ciphertext || HMAC-SHA256(header || ciphertext)
+//
+// where the header names everything needed to decrypt but the password:
+//
+//	"GCPB" || version 1
+//	len(cipher) || cipher, a registered name such as "aes-256-ctr"
+//	KDF: 1 || PBKDF2-HMAC-SHA256 iterations[4]
+//	  or 2 || log2(N) || r[4] || p[4] for scrypt
+//	  or 3 || time[4] || memory[4] || threads[4] for Argon2id, memory in KiB
+//	len(salt) || salt
+//
+// with lengths in one byte and other integers big endian.
+const (
+	pbeMagic    = "GCPB"
+	pbeVersion  = 1
+	pbeSaltSize = 16
+	pbeTagSize  = sha256.Size
+
+	pbeKDFPBKDF2   = 1
+	pbeKDFScrypt   = 2
+	pbeKDFArgon2id = 3
+)
+
+// pbeCiphers are the ciphers password-based encryption may use.
+var pbeCiphers = map[string]bool{
+	"aes-128-ctr":  true,
+	"aes-192-ctr":  true,
+	"aes-256-ctr":  true,
+	"des-ede3-ctr": true,
+}
+
+// PBEParams select the cipher and key derivation of PasswordEncrypt.
+type PBEParams struct {
+	// Cipher is "aes-128-ctr", "aes-192-ctr", "aes-256-ctr" or
+	// "des-ede3-ctr". If it is empty, "aes-256-ctr" is used.
+	Cipher string
+
+	// KDF is "scrypt", "pbkdf2" or "argon2id". If it is empty, "scrypt" is
+	// used.
+	KDF string
+
+	// Iter is the number of PBKDF2 iterations, 600000 if it is 0.
+	Iter int
+
+	// LogN is log2 of the scrypt cost parameter N, R its block size and P
+	// its parallelization, 15, 8 and 1 where they are 0.
+	LogN, R, P int
+
+	// Time is the number of Argon2id passes, Memory its memory in KiB and
+	// Threads its lanes, 3, 65536 and 4 where they are 0, as RFC 9106
+	// recommends where memory is scarce.
+	Time, Memory, Threads int
+}
+
+// withDefaults returns a copy of p with the zero fields set to their
+// defaults, checked against the limits that also apply when decrypting.
+func (p *PBEParams) withDefaults() (PBEParams, error) {
+	q := PBEParams{Cipher: "aes-256-ctr", KDF: "scrypt"}
+	if p != nil {
+		q = *p
+		if q.Cipher == "" {
+			q.Cipher = "aes-256-ctr"
+		}
+		if q.KDF == "" {
+			q.KDF = "scrypt"
+		}
+	}
+	switch q.KDF {
+	case "pbkdf2":
+		if q.Iter == 0 {
+			q.Iter = 600000
+		}
+	case "scrypt":
+		if q.LogN == 0 {
+			q.LogN = 15
+		}
+		if q.R == 0 {
+			q.R = 8
+		}
+		if q.P == 0 {
+			q.P = 1
+		}
+	case "argon2id":
+		if q.Time == 0 {
+			q.Time = 3
+		}
+		if q.Memory == 0 {
+			q.Memory = 64 << 10
+		}
+		if q.Threads == 0 {
+			q.Threads = 4
+		}
+	}
+	return q, q.check()
+}
+
+// check rejects unknown ciphers and KDFs and costs that would take too long
+// or, for scrypt and Argon2id, more than 1 GiB of memory, so that a header
+// cannot make PasswordDecrypt run for hours.
+func (p *PBEParams) check() error {
+	if !pbeCiphers[p.Cipher] {
+		return fmt.Errorf("cipher %q cannot be used with a password", p.Cipher)
+	}
+	switch p.KDF {
+	case "pbkdf2":
+		if p.Iter < 1 || p.Iter > 1<<24 {
+			return fmt.Errorf("invalid PBKDF2 iteration count %d", p.Iter)
+		}
+	case "scrypt":
+		if p.LogN < 1 || p.LogN > 24 || p.R < 1 || p.P < 1 || p.R > 1<<30/128>>p.LogN || p.P > 16 {
+			return fmt.Errorf("invalid scrypt parameters")
+		}
+	case "argon2id":
+		if p.Time < 1 || p.Time > 16 || p.Threads < 1 || p.Threads > 16 || p.Memory < 8*p.Threads || p.Memory > 1<<20 {
+			return fmt.Errorf("invalid Argon2id parameters")
+		}
+	default:
+		return fmt.Errorf("unknown KDF %q", p.KDF)
+	}
+	return nil
+}
+
+// header returns the header for params p and salt.
+func (p *PBEParams) header(salt []byte) []byte {
+	h := append([]byte(pbeMagic), pbeVersion, byte(len(p.Cipher)))
+	h = append(h, p.Cipher...)
+	var b [4]byte
+	switch p.KDF {
+	case "pbkdf2":
+		h = append(h, pbeKDFPBKDF2)
+		binary.BigEndian.PutUint32(b[:], uint32(p.Iter))
+		h = append(h, b[:]...)
+	case "argon2id":
+		h = append(h, pbeKDFArgon2id)
+		for _, v := range []int{p.Time, p.Memory, p.Threads} {
+			binary.BigEndian.PutUint32(b[:], uint32(v))
+			h = append(h, b[:]...)
+		}
+	default:
+		h = append(h, pbeKDFScrypt, byte(p.LogN))
+		binary.BigEndian.PutUint32(b[:], uint32(p.R))
+		h = append(h, b[:]...)
+		binary.BigEndian.PutUint32(b[:], uint32(p.P))
+		h = append(h, b[:]...)
+	}
+	h = append(h, byte(len(salt)))
+	return append(h, salt...)
+}
+
+// parsePBEHeader parses the header at the start of data and returns the
+// parameters, the salt and the length of the header.
+func parsePBEHeader(data []byte) (PBEParams, []byte, int, error) {
+	var p PBEParams
+	errTruncated := fmt.Errorf("truncated header")
+	if len(data) < len(pbeMagic)+2 || string(data[:len(pbeMagic)]) != pbeMagic {
+		return p, nil, 0, fmt.Errorf("not password-encrypted data")
+	}
+	if v := data[len(pbeMagic)]; v != pbeVersion {
+		return p, nil, 0, fmt.Errorf("unsupported version %d", v)
+	}
+	b := data[len(pbeMagic)+1:]
+	n := int(b[0])
+	if len(b) < 1+n+1 {
+		return p, nil, 0, errTruncated
+	}
+	p.Cipher, b = string(b[1:1+n]), b[1+n:]
+	switch kdf := b[0]; kdf {
+	case pbeKDFPBKDF2:
+		if len(b) < 5 {
+			return p, nil, 0, errTruncated
+		}
+		p.KDF, p.Iter, b = "pbkdf2", int(binary.BigEndian.Uint32(b[1:])), b[5:]
+	case pbeKDFScrypt:
+		if len(b) < 10 {
+			return p, nil, 0, errTruncated
+		}
+		p.KDF, p.LogN = "scrypt", int(b[1])
+		p.R, p.P = int(binary.BigEndian.Uint32(b[2:])), int(binary.BigEndian.Uint32(b[6:]))
+		b = b[10:]
+	case pbeKDFArgon2id:
+		if len(b) < 13 {
+			return p, nil, 0, errTruncated
+		}
+		p.KDF, p.Time = "argon2id", int(binary.BigEndian.Uint32(b[1:]))
+		p.Memory, p.Threads = int(binary.BigEndian.Uint32(b[5:])), int(binary.BigEndian.Uint32(b[9:]))
+		b = b[13:]
+	default:
+		return p, nil, 0, fmt.Errorf("unknown KDF %d", kdf)
+	}
+	if len(b) < 1 || len(b) < 1+int(b[0]) {
+		return p, nil, 0, errTruncated
+	}
+	if b[0] < 8 {
+		return p, nil, 0, fmt.Errorf("salt too short")
+	}
+	salt := b[1 : 1+int(b[0])]
+	if err := p.check(); err != nil {
+		return p, nil, 0, err
+	}
+	return p, salt, len(data) - len(b) + 1 + len(salt), nil
+}
+
+// deriveKeys derives the cipher key, IV and MAC key for p from password
+// and salt.
+func (p *PBEParams) deriveKeys(password, salt []byte) (key, iv, macKey []byte, err error) {
+	c, err := registry.LookupCipher(p.Cipher)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	n := c.KeySize + c.IVSize + pbeTagSize
+	var dk []byte
+	switch p.KDF {
+	case "pbkdf2":
+		dk = pbkdf2Key(sha256.New, password, salt, p.Iter, n)
+	case "argon2id":
+		dk, err = argon2idKey(password, salt, nil, nil, p.Time, p.Memory, p.Threads, n)
+	default:
+		dk, err = scryptKey(password, salt, 1<<p.LogN, p.R, p.P, n)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return dk[:c.KeySize], dk[c.KeySize : c.KeySize+c.IVSize], dk[c.KeySize+c.IVSize:], nil
+}
+
+// PasswordEncrypt encrypts plaintext under a key derived from password with
+// a random salt. If params is nil, AES-256 and scrypt are used.
+func PasswordEncrypt(password, plaintext []byte, params *PBEParams) ([]byte, error) {
+	p, err := params.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, pbeSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	key, iv, macKey, err := p.deriveKeys(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	c, _ := registry.LookupCipher(p.Cipher)
+	s, err := c.NewStream(key, iv, false)
+	if err != nil {
+		return nil, err
+	}
+
+	out := p.header(salt)
+	n := len(out)
+	out = append(out, make([]byte, len(plaintext))...)
+	s.XORKeyStream(out[n:], plaintext)
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(out)
+	return mac.Sum(out), nil
+}
+
+// PasswordDecrypt decrypts the output of PasswordEncrypt with a key derived
+// from password, with the parameters given in its header. It returns an
+// error if the password is wrong or the data was modified.
+func PasswordDecrypt(password, data []byte) ([]byte, error) {
+	p, salt, n, err := parsePBEHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < n+pbeTagSize {
+		return nil, fmt.Errorf("truncated data")
+	}
+	key, iv, macKey, err := p.deriveKeys(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	body, tag := data[:len(data)-pbeTagSize], data[len(data)-pbeTagSize:]
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), tag) {
+		return nil, fmt.Errorf("wrong password or corrupted data")
+	}
+	c, _ := registry.LookupCipher(p.Cipher)
+	s, err := c.NewStream(key, iv, true)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(body)-n)
+	s.XORKeyStream(out, body[n:])
+	return out, nil
+}
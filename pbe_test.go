@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Computed with Python's hashlib and pyca/cryptography from the password
+// "correct-horse" and the salt 000102...0f.
+var pbeTests = []struct {
+	data string
+	want string
+}{
+	{
+		// aes-256-ctr, scrypt with N = 16, r = 1, p = 1
+		"47435042010b6165732d3235362d6374720204000000010000000110000102030405060708090a0b0c0d0e0f7495627db1e150058dbe314fe0493080ddc33cedc191cc00780a6583f124d1a40e8b8eb23e57dc3f7227a76725aa57650b1ca15eba3e9dc1ec7e6cee",
+		"attack at dawn, bring snacks",
+	},
+	{
+		// des-ede3-ctr, PBKDF2 with 1000 iterations
+		"47435042010c6465732d656465332d63747201000003e810000102030405060708090a0b0c0d0e0fcf5549c6b7f846e13406094db8911561dd3aaeef385d0b5ac50e55caba2647f840afecd95d7a352a72c2d1b99fd81407bef08d489879514c2a1e1caf",
+		"attack at dawn, bring snacks",
+	},
+}
+
+var pbePassword = []byte("correct-horse")
+
+func Test_PasswordDecrypt(t *testing.T) {
+	for i, tt := range pbeTests {
+		data, _ := hex.DecodeString(tt.data)
+		got, err := PasswordDecrypt(pbePassword, data)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("#%d: PasswordDecrypt() = %q, %v, want %q", i, got, err, tt.want)
+		}
+		if _, err := PasswordDecrypt([]byte("battery-staple"), data); err == nil {
+			t.Errorf("#%d: PasswordDecrypt() with the wrong password succeeded", i)
+		}
+		for _, j := range []int{5, 30, len(data) - 40, len(data) - 1} {
+			bad := append([]byte(nil), data...)
+			bad[j] ^= 1
+			if _, err := PasswordDecrypt(pbePassword, bad); err == nil {
+				t.Errorf("#%d: PasswordDecrypt() with byte %d flipped succeeded", i, j)
+			}
+		}
+		for _, n := range []int{0, 10, 30, len(data) - 1} {
+			if _, err := PasswordDecrypt(pbePassword, data[:n]); err == nil {
+				t.Errorf("#%d: PasswordDecrypt() of %d bytes succeeded", i, n)
+			}
+		}
+	}
+}
+
+func Test_PasswordEncrypt(t *testing.T) {
+	plaintext := commonInput[:50]
+	for _, params := range []*PBEParams{
+		{Cipher: "aes-128-ctr", KDF: "scrypt", LogN: 4, R: 2, P: 2},
+		{Cipher: "aes-192-ctr", KDF: "pbkdf2", Iter: 10},
+		{Cipher: "des-ede3-ctr", LogN: 5},
+		{Cipher: "aes-256-ctr", KDF: "argon2id", Time: 2, Memory: 64, Threads: 2},
+	} {
+		data, err := PasswordEncrypt(pbePassword, plaintext, params)
+		if err != nil {
+			t.Fatalf("%+v: PasswordEncrypt() = %s", params, err)
+		}
+		want, _ := params.withDefaults()
+		if got, salt, n, err := parsePBEHeader(data); err != nil || got != want || len(salt) != pbeSaltSize || len(data) != n+len(plaintext)+pbeTagSize {
+			t.Errorf("%+v: PasswordEncrypt() = %x, header %+v, %v", params, data, got, err)
+		}
+		again, _ := PasswordEncrypt(pbePassword, plaintext, params)
+		if bytes.Equal(data, again) {
+			t.Errorf("%+v: PasswordEncrypt() gave the same output twice", params)
+		}
+		if got, err := PasswordDecrypt(pbePassword, data); err != nil || !bytes.Equal(got, plaintext) {
+			t.Errorf("%+v: PasswordDecrypt() = %x, %v, want %x", params, got, err, plaintext)
+		}
+	}
+
+	for _, params := range []*PBEParams{
+		{Cipher: "des-ctr"},
+		{Cipher: "aes-128-cbc"},
+		{KDF: "bcrypt"},
+		{KDF: "argon2id", Time: 17},
+		{KDF: "argon2id", Memory: 1<<20 + 1},
+		{KDF: "argon2id", Memory: 32, Threads: 5},
+		{KDF: "argon2id", Threads: 17},
+		{KDF: "pbkdf2", Iter: 1 << 25},
+		{LogN: 25},
+		{LogN: 20, R: 16},
+		{P: 17},
+	} {
+		if _, err := PasswordEncrypt(pbePassword, plaintext, params); err == nil {
+			t.Errorf("%+v: PasswordEncrypt() succeeded", params)
+		}
+	}
+}
+
+func Test_parsePBEHeader(t *testing.T) {
+	// A header may not ask for more work than PasswordEncrypt would do.
+	p := &PBEParams{Cipher: "aes-256-ctr", KDF: "scrypt", LogN: 30, R: 8, P: 1}
+	data := append(p.header(make([]byte, 16)), make([]byte, pbeTagSize)...)
+	if _, _, _, err := parsePBEHeader(data); err == nil || !strings.Contains(err.Error(), "scrypt") {
+		t.Errorf("parsePBEHeader() with N = 2^30 = %v, want an error", err)
+	}
+	p = &PBEParams{Cipher: "aes-256-ctr", KDF: "argon2id", Time: 1, Memory: 4 << 20, Threads: 1}
+	data = append(p.header(make([]byte, 16)), make([]byte, pbeTagSize)...)
+	if _, _, _, err := parsePBEHeader(data); err == nil || !strings.Contains(err.Error(), "Argon2id") {
+		t.Errorf("parsePBEHeader() with 4 GiB of Argon2id memory = %v, want an error", err)
+	}
+	if _, _, _, err := parsePBEHeader(data[:len(pbeMagic)+2+len(p.Cipher)+12]); err == nil {
+		t.Errorf("parsePBEHeader() of a truncated Argon2id header succeeded")
+	}
+	p = &PBEParams{Cipher: "aes-256-ctr", KDF: "pbkdf2", Iter: 1000}
+	data = append(p.header(make([]byte, 4)), make([]byte, pbeTagSize)...)
+	if _, _, _, err := parsePBEHeader(data); err == nil {
+		t.Errorf("parsePBEHeader() with a 4-byte salt succeeded")
+	}
+}
+
+func Test_run_password(t *testing.T) {
+	plaintext := "The quick brown fox jumps over the lazy dog"
+	for _, alg := range []string{"aes", "3des", "aes -kdf argon2id"} {
+		args := append([]string{"encrypt", "-pass", "pass:correct-horse", "-alg"}, strings.Fields(alg)...)
+		ct, errOut, code := runCommand([]byte(plaintext), args...)
+		if code != 0 {
+			t.Fatalf("%s: encrypt = %q, %d", alg, errOut, code)
+		}
+		out, errOut, code := runCommand([]byte(ct), "decrypt", "-pass", "pass:correct-horse", "-outform", "raw")
+		if code != 0 || out != plaintext {
+			t.Errorf("%s: decrypt = %q, %q, %d, want %q", alg, out, errOut, code, plaintext)
+		}
+		if _, _, code := runCommand([]byte(ct), "decrypt", "-pass", "pass:battery-staple"); code != 1 {
+			t.Errorf("%s: decrypt with the wrong password exit status %d, want 1", alg, code)
+		}
+	}
+	for _, args := range [][]string{
+		{"encrypt", "-pass", "pass:x", "-key", "00"},
+		{"encrypt", "-pass", "pass:x", "-mode", "cbc"},
+		{"encrypt", "-pass", "pass:x", "-alg", "des"},
+	} {
+		if _, _, code := runCommand(nil, args...); code != 1 {
+			t.Errorf("%s: exit status %d, want 1", strings.Join(args, " "), code)
+		}
+	}
+}
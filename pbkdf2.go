@@ -0,0 +1,36 @@
+// RFC 8018: PKCS #5: Password-Based Cryptography Specification Version 2.1, Section 5.2
+// https://www.rfc-editor.org/rfc/rfc8018#section-5.2
+
+package main
+
+import (
+	"crypto/hmac"
+	"encoding/binary"
+	"hash"
+)
+
+// pbkdf2Key derives a key of keyLen bytes from password and salt with
+// PBKDF2, using HMAC with the hash function h as the pseudorandom function.
+func pbkdf2Key(h func() hash.Hash, password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(h, password)
+	n := prf.Size()
+	var buf [4]byte
+	dk := make([]byte, 0, (keyLen+n-1)/n*n)
+	u := make([]byte, n)
+	for i := uint32(1); len(dk) < keyLen; i++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf[:], i)
+		prf.Write(buf[:])
+		t := prf.Sum(nil)
+		copy(u, t)
+		for j := 1; j < iter; j++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			xorBytes(t, t, u)
+		}
+		dk = append(dk, t...)
+	}
+	return dk[:keyLen]
+}
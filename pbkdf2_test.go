@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"testing"
+)
+
+// RFC 6070 and the SHA-256 vectors of RFC 7914, Section 11.
+var pbkdf2Tests = []struct {
+	h        func() hash.Hash
+	password string
+	salt     string
+	iter     int
+	dk       string
+}{
+	{sha1.New, "password", "salt", 1, "0c60c80f961f0e71f3a9b524af6012062fe037a6"},
+	{sha1.New, "password", "salt", 2, "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957"},
+	{sha1.New, "password", "salt", 4096, "4b007901b765489abead49d926f721d065a429c1"},
+	{sha1.New, "passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, "3d2eec4fe41c849b80c8d83662c0e44a8b291a964cf2f07038"},
+	{sha1.New, "pass\x00word", "sa\x00lt", 4096, "56fa6aa75548099dcc37d7f03425e0c3"},
+	{sha256.New, "passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+}
+
+func Test_pbkdf2Key(t *testing.T) {
+	for i, tt := range pbkdf2Tests {
+		want, _ := hex.DecodeString(tt.dk)
+		if got := pbkdf2Key(tt.h, []byte(tt.password), []byte(tt.salt), tt.iter, len(want)); !bytes.Equal(got, want) {
+			t.Errorf("#%d: pbkdf2Key() = %x, want %x", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// ISO 9564-1:2017: Financial services, Personal Identification Number (PIN) management and security, Part 1: Basic principles and requirements for PINs in card-based systems
+
+package main
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/AirWSW/go-crypto/aes"
+	"github.com/AirWSW/go-crypto/des"
+)
+
+// PINBlockFormat is an ISO 9564 PIN block format. Formats 0, 1 and 3 are
+// 8-byte blocks encrypted with 3DES, format 4 a 16-byte block encrypted
+// with AES.
+type PINBlockFormat int
+
+const (
+	// PINBlockISO0 pads the PIN with F and XORs it with 12 digits of the
+	// PAN.
+	PINBlockISO0 PINBlockFormat = 0
+
+	// PINBlockISO1 pads the PIN with random digits and does not use the
+	// PAN.
+	PINBlockISO1 PINBlockFormat = 1
+
+	// PINBlockISO3 is format 0 with random padding from A to F.
+	PINBlockISO3 PINBlockFormat = 3
+
+	// PINBlockISO4 pads the PIN with A and 8 random bytes, and enciphers it
+	// twice around an XOR with the whole PAN.
+	PINBlockISO4 PINBlockFormat = 4
+)
+
+// errPINBlock is returned for any decrypted PIN block that is malformed,
+// so that neither the PIN nor which check failed is revealed.
+var errPINBlock = fmt.Errorf("invalid PIN block")
+
+// EncryptPINBlock returns the PIN block of pin, from 4 to 12 digits, in
+// format f, encrypted under key: a 16 or 24-byte 3DES key for formats 0, 1
+// and 3, or an AES key for format 4. pan is the primary account number,
+// which format 1 does not use.
+func EncryptPINBlock(f PINBlockFormat, key []byte, pin, pan string) ([]byte, error) {
+	return encryptPINBlock(f, key, pin, pan, rand.Reader)
+}
+
+func encryptPINBlock(f PINBlockFormat, key []byte, pin, pan string, rnd io.Reader) ([]byte, error) {
+	block, err := pinBlockCipher(f, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(pin) < 4 || len(pin) > 12 || !isDigits(pin) {
+		return nil, fmt.Errorf("PIN must be 4 to 12 digits")
+	}
+	panField, err := pinBlockPAN(f, pan)
+	if err != nil {
+		return nil, err
+	}
+
+	// The control field, the PIN length and the PIN, then the fill.
+	nibbles := make([]byte, 2*block.BlockSize())
+	nibbles[0], nibbles[1] = byte(f), byte(len(pin))
+	for i := 0; i < len(pin); i++ {
+		nibbles[2+i] = pin[i] - '0'
+	}
+	fill := make([]byte, len(nibbles)-2-len(pin))
+	if _, err := io.ReadFull(rnd, fill); err != nil {
+		return nil, err
+	}
+	for i, r := range fill {
+		switch {
+		case f == PINBlockISO0:
+			r = 0xf
+		case f == PINBlockISO1:
+			r &= 0xf
+		case f == PINBlockISO3:
+			r = 0xa + r%6
+		case i < 14-len(pin):
+			r = 0xa
+		default:
+			r &= 0xf
+		}
+		nibbles[2+len(pin)+i] = r
+	}
+	out := make([]byte, block.BlockSize())
+	for i := range out {
+		out[i] = nibbles[2*i]<<4 | nibbles[2*i+1]
+	}
+
+	if f == PINBlockISO4 {
+		block.Encrypt(out, out)
+		xorBytes(out, out, panField)
+		block.Encrypt(out, out)
+		return out, nil
+	}
+	xorBytes(out, out, panField)
+	block.Encrypt(out, out)
+	return out, nil
+}
+
+// DecryptPINBlock returns the PIN in a PIN block encrypted by
+// EncryptPINBlock with the same format, key and PAN.
+func DecryptPINBlock(f PINBlockFormat, key, pinBlock []byte, pan string) (string, error) {
+	block, err := pinBlockCipher(f, key)
+	if err != nil {
+		return "", err
+	}
+	if len(pinBlock) != block.BlockSize() {
+		return "", fmt.Errorf("PIN block must be %d bytes", block.BlockSize())
+	}
+	panField, err := pinBlockPAN(f, pan)
+	if err != nil {
+		return "", err
+	}
+
+	b := make([]byte, len(pinBlock))
+	block.Decrypt(b, pinBlock)
+	xorBytes(b, b, panField)
+	if f == PINBlockISO4 {
+		block.Decrypt(b, b)
+	}
+	nibbles := make([]byte, 2*len(b))
+	for i, c := range b {
+		nibbles[2*i], nibbles[2*i+1] = c>>4, c&0xf
+	}
+
+	n := int(nibbles[1])
+	if nibbles[0] != byte(f) || n < 4 || n > 12 {
+		return "", errPINBlock
+	}
+	pin := make([]byte, n)
+	for i := range pin {
+		d := nibbles[2+i]
+		if d > 9 {
+			return "", errPINBlock
+		}
+		pin[i] = '0' + d
+	}
+	for i, r := range nibbles[2+n:] {
+		switch {
+		case f == PINBlockISO0 && r != 0xf,
+			f == PINBlockISO3 && r < 0xa,
+			f == PINBlockISO4 && i < 14-n && r != 0xa:
+			return "", errPINBlock
+		}
+	}
+	return string(pin), nil
+}
+
+// TranslatePINBlock decrypts a PIN block in format from under fromKey and
+// encrypts the PIN again in format to under toKey, without returning it.
+func TranslatePINBlock(from PINBlockFormat, fromKey, pinBlock []byte, to PINBlockFormat, toKey []byte, pan string) ([]byte, error) {
+	pin, err := DecryptPINBlock(from, fromKey, pinBlock, pan)
+	if err != nil {
+		return nil, err
+	}
+	return EncryptPINBlock(to, toKey, pin, pan)
+}
+
+// pinBlockCipher returns the cipher of format f under key.
+func pinBlockCipher(f PINBlockFormat, key []byte) (cipher.Block, error) {
+	switch f {
+	case PINBlockISO0, PINBlockISO1, PINBlockISO3:
+		if len(key) == 16 {
+			return newTwoKeyTDES(key)
+		}
+		if len(key) != 24 {
+			return nil, fmt.Errorf("invalid key size")
+		}
+		return des.NewTripleDESCipher(key)
+	case PINBlockISO4:
+		return aes.NewCipher(key)
+	}
+	return nil, fmt.Errorf("unsupported PIN block format %d", f)
+}
+
+// pinBlockPAN returns the PAN field XORed into a PIN block of format f:
+// zero for format 1; for formats 0 and 3, the 12 rightmost digits of the
+// PAN without the check digit after four zeros; for format 4, the number
+// of digits beyond 12 and the PAN, padded to 12 digits with zeros on the
+// left and to 32 digits on the right.
+func pinBlockPAN(f PINBlockFormat, pan string) ([]byte, error) {
+	var digits string
+	switch f {
+	case PINBlockISO1:
+		return make([]byte, 8), nil
+	case PINBlockISO0, PINBlockISO3:
+		if len(pan) < 2 || len(pan) > 19 || !isDigits(pan) {
+			return nil, fmt.Errorf("invalid PAN")
+		}
+		digits = pan[:len(pan)-1]
+		if len(digits) > 12 {
+			digits = digits[len(digits)-12:]
+		}
+		digits = strings.Repeat("0", 16-len(digits)) + digits
+	case PINBlockISO4:
+		if len(pan) < 1 || len(pan) > 19 || !isDigits(pan) {
+			return nil, fmt.Errorf("invalid PAN")
+		}
+		if len(pan) < 12 {
+			digits = "0" + strings.Repeat("0", 12-len(pan)) + pan
+		} else {
+			digits = fmt.Sprint(len(pan)-12) + pan
+		}
+		digits += strings.Repeat("0", 32-len(digits))
+	}
+	return hex.DecodeString(digits)
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
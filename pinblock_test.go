@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var (
+	pinBlockTDESKey = hexBytes("0123456789abcdeffedcba9876543210")
+	pinBlockAESKey  = hexBytes("00112233445566778899aabbccddeeff")
+)
+
+// The fill is given as the nibbles after the PIN in the clear PIN block.
+var pinBlockTests = []struct {
+	format PINBlockFormat
+	key    []byte
+	pin    string
+	pan    string
+	fill   string
+	block  string
+}{
+	{PINBlockISO0, pinBlockTDESKey, "1234", "4012345678909", "ffffffffff", "c03d21cdbcb0c58b"},
+	{PINBlockISO0, pinBlockTDESKey, "123456789012", "5432101234567891", "ff", "5f5c74145fefdab9"},
+	{PINBlockISO1, pinBlockTDESKey, "1234", "", "0123456789", "646855a2370347d8"},
+	{PINBlockISO1, pinBlockTDESKey, "98765", "", "fedcba987", "e42c66991ebfa2cf"},
+	{PINBlockISO3, pinBlockTDESKey, "1234", "4012345678909", "abcdefabcd", "e60887f6a1fdefec"},
+	{PINBlockISO3, pinBlockTDESKey, "5678", "43219876543210987", "fedcbafedc", "7002b03f1b9ab411"},
+	{PINBlockISO4, pinBlockAESKey, "1234", "1234567890123456789", "aaaaaaaaaa0123456789abcdef", "d7701da6e63afb5dd0bb62aa66b71eeb"},
+	{PINBlockISO4, pinBlockAESKey, "123456789012", "12345678901", "aafedcba9876543210", "28c50f979091c65443c9353d375cc65f"},
+	{PINBlockISO4, hexBytes("00112233445566778899aabbccddeeff0011223344556677"), "0000", "432198765432109870", "aaaaaaaaaa00000000ffffffff", "7007587bf681d040adfc85b247793898"},
+}
+
+// pinBlockFill returns the random bytes from which encryptPINBlock makes
+// the given fill in format f.
+func pinBlockFill(f PINBlockFormat, fill string) []byte {
+	b := make([]byte, len(fill))
+	for i := range fill {
+		b[i] = hexBytes("0" + fill[i:i+1])[0]
+		if f == PINBlockISO3 {
+			b[i] -= 0xa
+		}
+	}
+	return b
+}
+
+func Test_encryptPINBlock(t *testing.T) {
+	for i, tt := range pinBlockTests {
+		got, err := encryptPINBlock(tt.format, tt.key, tt.pin, tt.pan, bytes.NewReader(pinBlockFill(tt.format, tt.fill)))
+		if err != nil {
+			t.Fatalf("#%d: encryptPINBlock() = %s", i, err)
+		}
+		if want := hexBytes(tt.block); !bytes.Equal(got, want) {
+			t.Errorf("#%d: encryptPINBlock() = %x, want %x", i, got, want)
+		}
+		if pin, err := DecryptPINBlock(tt.format, tt.key, got, tt.pan); pin != tt.pin || err != nil {
+			t.Errorf("#%d: DecryptPINBlock() = %q, %v, want %q", i, pin, err, tt.pin)
+		}
+	}
+}
+
+func Test_EncryptPINBlock(t *testing.T) {
+	for i, tt := range pinBlockTests {
+		b1, err := EncryptPINBlock(tt.format, tt.key, tt.pin, tt.pan)
+		if err != nil {
+			t.Fatalf("#%d: EncryptPINBlock() = %s", i, err)
+		}
+		b2, _ := EncryptPINBlock(tt.format, tt.key, tt.pin, tt.pan)
+		if random := tt.format != PINBlockISO0; bytes.Equal(b1, b2) == random {
+			t.Errorf("#%d: EncryptPINBlock() twice = %x, %x", i, b1, b2)
+		}
+		if pin, err := DecryptPINBlock(tt.format, tt.key, b1, tt.pan); pin != tt.pin || err != nil {
+			t.Errorf("#%d: DecryptPINBlock() = %q, %v, want %q", i, pin, err, tt.pin)
+		}
+	}
+}
+
+func Test_TranslatePINBlock(t *testing.T) {
+	const pin, pan = "24680", "4111111111111111"
+	keys := map[PINBlockFormat][]byte{
+		PINBlockISO0: pinBlockTDESKey,
+		PINBlockISO1: hexBytes("0123456789abcdeffedcba98765432100011223344556677"),
+		PINBlockISO3: pinBlockAESKey,
+		PINBlockISO4: hexBytes("00112233445566778899aabbccddeeff0123456789abcdeffedcba9876543210"),
+	}
+	formats := []PINBlockFormat{PINBlockISO0, PINBlockISO1, PINBlockISO3, PINBlockISO4}
+	for _, from := range formats {
+		for _, to := range formats {
+			in, _ := EncryptPINBlock(from, keys[from], pin, pan)
+			out, err := TranslatePINBlock(from, keys[from], in, to, keys[to], pan)
+			if err != nil {
+				t.Errorf("%d to %d: TranslatePINBlock() = %s", from, to, err)
+				continue
+			}
+			if got, err := DecryptPINBlock(to, keys[to], out, pan); got != pin || err != nil {
+				t.Errorf("%d to %d: DecryptPINBlock() = %q, %v, want %q", from, to, got, err, pin)
+			}
+		}
+	}
+}
+
+// Test_DecryptPINBlock_Errors checks that a PIN block decrypted with the
+// wrong key or PAN is rejected, and that no error shows the PIN.
+func Test_DecryptPINBlock_Errors(t *testing.T) {
+	for i, tt := range pinBlockTests {
+		block := hexBytes(tt.block)
+		wrongKey := append([]byte(nil), tt.key...)
+		wrongKey[0] ^= 0x80
+		check := func(what string, pin string, err error) {
+			if err == nil {
+				// A wrong key may still give a valid block by chance, but
+				// not with these vectors.
+				t.Errorf("#%d: DecryptPINBlock() with %s = %q", i, what, pin)
+			} else if strings.Contains(err.Error(), tt.pin) || strings.ContainsAny(err.Error(), "0123456789") {
+				t.Errorf("#%d: DecryptPINBlock() with %s = %q", i, what, err)
+			}
+		}
+		pin, err := DecryptPINBlock(tt.format, wrongKey, block, tt.pan)
+		check("wrong key", pin, err)
+		if tt.format != PINBlockISO1 {
+			pin, err = DecryptPINBlock(tt.format, tt.key, block, "5500000000000004")
+			check("wrong PAN", pin, err)
+		}
+	}
+}
+
+func Test_PINBlock_Invalid(t *testing.T) {
+	for i, tt := range []struct {
+		format PINBlockFormat
+		key    []byte
+		pin    string
+		pan    string
+	}{
+		{PINBlockISO0, pinBlockTDESKey, "123", "4012345678909"},
+		{PINBlockISO0, pinBlockTDESKey, "1234567890123", "4012345678909"},
+		{PINBlockISO0, pinBlockTDESKey, "12a4", "4012345678909"},
+		{PINBlockISO0, pinBlockTDESKey, "1234", "4012-345678909"},
+		{PINBlockISO0, pinBlockTDESKey, "1234", "40123456789090000000"},
+		{PINBlockISO0, pinBlockTDESKey[:8], "1234", "4012345678909"},
+		{PINBlockISO4, pinBlockAESKey, "1234", ""},
+		{PINBlockISO4, pinBlockAESKey[:15], "1234", "4012345678909"},
+		{PINBlockFormat(2), pinBlockTDESKey, "1234", "4012345678909"},
+	} {
+		if _, err := EncryptPINBlock(tt.format, tt.key, tt.pin, tt.pan); err == nil {
+			t.Errorf("#%d: EncryptPINBlock() succeeded", i)
+		}
+	}
+	if _, err := DecryptPINBlock(PINBlockISO4, pinBlockAESKey, make([]byte, 8), "4012345678909"); err == nil {
+		t.Errorf("DecryptPINBlock() accepted an 8-byte format 4 block")
+	}
+}
@@ -0,0 +1,71 @@
+// RFC 8452: AES-GCM-SIV: Nonce Misuse-Resistant Authenticated Encryption
+// https://www.rfc-editor.org/rfc/rfc8452
+
+package main
+
+// POLYVAL is the universal hash function of AES-GCM-SIV keyed by H. It
+// implements hash.Hash; input that does not fill a whole block is padded
+// with zeros when the sum is taken.
+//
+// POLYVAL works in the same field as GHASH with the bytes of each block in
+// little-endian order, so it is computed with GHASH as described in
+// Appendix A of RFC 8452.
+type POLYVAL struct {
+	g   GHASH
+	buf [GHASHSize]byte
+	n   int
+}
+
+// NewPOLYVAL returns a new POLYVAL keyed by the 16-byte key h.
+func NewPOLYVAL(h []byte) *POLYVAL {
+	if len(h) != GHASHSize {
+		panic("invalid POLYVAL key length")
+	}
+	var k [GHASHSize]byte
+	copy(k[:], h)
+	reverseBytes(k[:])
+	p := new(POLYVAL)
+	p.g.h = gcmFieldElementFromBytes(k[:]).mulX()
+	return p
+}
+
+func (p *POLYVAL) Size() int { return GHASHSize }
+
+func (p *POLYVAL) BlockSize() int { return GHASHSize }
+
+func (p *POLYVAL) Reset() {
+	p.g.Reset()
+	p.n = 0
+}
+
+func (p *POLYVAL) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		m := copy(p.buf[p.n:], b)
+		p.n += m
+		b = b[m:]
+		if p.n == GHASHSize {
+			p.update()
+		}
+	}
+	return n, nil
+}
+
+func (p *POLYVAL) update() {
+	reverseBytes(p.buf[:])
+	p.g.update(p.buf[:])
+	p.n = 0
+}
+
+func (p *POLYVAL) Sum(b []byte) []byte {
+	d := *p
+	if d.n > 0 {
+		for i := d.n; i < GHASHSize; i++ {
+			d.buf[i] = 0
+		}
+		d.update()
+	}
+	out := d.g.Sum(nil)
+	reverseBytes(out)
+	return append(b, out...)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// RFC 8452 Appendix A. The Relationship between POLYVAL and GHASH
+var polyvalTests = []struct {
+	h   []byte
+	in  []byte
+	out []byte
+}{
+	{
+		[]byte{0x25, 0x62, 0x93, 0x47, 0x58, 0x92, 0x42, 0x76, 0x1d, 0x31, 0xf8, 0x26, 0xba, 0x4b, 0x75, 0x7b},
+		[]byte{
+			0x4f, 0x4f, 0x95, 0x66, 0x8c, 0x83, 0xdf, 0xb6, 0x40, 0x17, 0x62, 0xbb, 0x2d, 0x01, 0xa2, 0x62,
+			0xd1, 0xa2, 0x4d, 0xdd, 0x27, 0x21, 0xd0, 0x06, 0xbb, 0xe4, 0x5f, 0x20, 0xd3, 0xc9, 0xf3, 0x62,
+		},
+		[]byte{0xf7, 0xa3, 0xb4, 0x7b, 0x84, 0x61, 0x19, 0xfa, 0xe5, 0xb7, 0x86, 0x6c, 0xf5, 0xe5, 0xb7, 0x7e},
+	},
+}
+
+func Test_POLYVAL_Sum(t *testing.T) {
+	for i, tt := range polyvalTests {
+		p := NewPOLYVAL(tt.h)
+		p.Write(tt.in)
+		if got := p.Sum(nil); !bytes.Equal(got, tt.out) {
+			t.Errorf("#%d: POLYVAL.Sum() = %x, want %x", i, got, tt.out)
+		}
+
+		p.Reset()
+		p.Write(tt.in[:7])
+		p.Write(tt.in[7:])
+		if got := p.Sum(nil); !bytes.Equal(got, tt.out) {
+			t.Errorf("#%d: POLYVAL.Sum() after split writes = %x, want %x", i, got, tt.out)
+		}
+	}
+}
+
+func Test_POLYVAL_Padding(t *testing.T) {
+	p := NewPOLYVAL(polyvalTests[0].h)
+	p.Write(commonInput[:20])
+	partial := p.Sum(nil)
+	p.Reset()
+	p.Write(commonInput[:20])
+	p.Write(make([]byte, 12))
+	if got := p.Sum(nil); !bytes.Equal(got, partial) {
+		t.Errorf("POLYVAL.Sum() = %x, want zero padded %x", got, partial)
+	}
+}
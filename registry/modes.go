@@ -0,0 +1,36 @@
+package registry
+
+import (
+	"crypto/cipher"
+	"fmt"
+
+	"github.com/AirWSW/go-crypto/internal/modes"
+)
+
+// NewMode returns either a BlockMode or a Stream for block in the given
+// mode: "ecb", "cbc", "cfb", "cfb8", "ofb" or "ctr". The block modes
+// encrypt whole blocks without padding. ECB takes no IV, and the other
+// modes an IV of the block size.
+func NewMode(block cipher.Block, mode string, iv []byte, decrypt bool) (cipher.BlockMode, cipher.Stream, error) {
+	if mode != "ecb" && len(iv) != block.BlockSize() {
+		return nil, nil, fmt.Errorf("invalid IV length")
+	}
+	switch mode {
+	case "ecb":
+		return modes.NewECB(block, decrypt), nil, nil
+	case "cbc":
+		if decrypt {
+			return cipher.NewCBCDecrypter(block, iv), nil, nil
+		}
+		return cipher.NewCBCEncrypter(block, iv), nil, nil
+	case "cfb":
+		return nil, modes.NewCFB(block, iv, decrypt), nil
+	case "cfb8":
+		return nil, modes.NewCFB8(block, iv, decrypt), nil
+	case "ofb":
+		return nil, modes.NewOFB(block, iv), nil
+	case "ctr":
+		return nil, modes.NewCTR(block, iv), nil
+	}
+	return nil, nil, fmt.Errorf("unknown mode %q", mode)
+}
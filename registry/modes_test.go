@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/AirWSW/go-crypto/aes"
+)
+
+func hexBytes(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+var (
+	testKey   = hexBytes("2b7e151628aed2a6abf7158809cf4f3c")
+	testInput = hexBytes("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710")
+)
+
+// NIST SP 800-38A Appendix F: the AES-128 examples of each mode.
+var modeTests = []struct {
+	mode string
+	iv   string
+	out  string
+}{
+	{"ecb", "", "3ad77bb40d7a3660a89ecaf32466ef97f5d3d58503b9699de785895a96fdbaaf43b1cd7f598ece23881b00e3ed0306887b0c785e27e8ad3f8223207104725dd4"},
+	{"cbc", "000102030405060708090a0b0c0d0e0f", "7649abac8119b246cee98e9b12e9197d5086cb9b507219ee95db113a917678b273bed6b8e3c1743b7116e69e222295163ff1caa1681fac09120eca307586e1a7"},
+	{"cfb8", "000102030405060708090a0b0c0d0e0f", "3b79424c9c0dd436bace9e0ed4586a4f32b9"},
+	{"cfb", "000102030405060708090a0b0c0d0e0f", "3b3fd92eb72dad20333449f8e83cfb4ac8a64537a0b3a93fcde3cdad9f1ce58b26751f67a3cbb140b1808cf187a4f4dfc04b05357c5d1c0eeac4c66f9ff7f2e6"},
+	{"ofb", "000102030405060708090a0b0c0d0e0f", "3b3fd92eb72dad20333449f8e83cfb4a7789508d16918f03f53c52dac54ed8259740051e9c5fecf64344f7a82260edcc304c6528f659c77866a510d9c1d6ae5e"},
+	{"ctr", "f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff", "874d6191b620e3261bef6864990db6ce9806f66b7970fdff8617187bb9fffdff5ae4df3edbd5d35e5b4f09020db03eab1e031dda2fbe03d1792170a0f3009cee"},
+}
+
+// modeCrypt runs in through the mode in one call.
+func modeCrypt(t *testing.T, mode string, iv, in []byte, decrypt bool) []byte {
+	block, _ := aes.NewCipher(testKey)
+	bm, s, err := NewMode(block, mode, iv, decrypt)
+	if err != nil {
+		t.Fatalf("NewMode(%s) = %s", mode, err)
+	}
+	out := make([]byte, len(in))
+	if bm != nil {
+		bm.CryptBlocks(out, in)
+	} else {
+		s.XORKeyStream(out, in)
+	}
+	return out
+}
+
+func Test_NewMode(t *testing.T) {
+	for _, tt := range modeTests {
+		iv, want := hexBytes(tt.iv), hexBytes(tt.out)
+		in := testInput[:len(want)]
+		if got := modeCrypt(t, tt.mode, iv, in, false); !bytes.Equal(got, want) {
+			t.Errorf("%s: encrypt = %x, want %x", tt.mode, got, want)
+		}
+		if got := modeCrypt(t, tt.mode, iv, want, true); !bytes.Equal(got, in) {
+			t.Errorf("%s: decrypt = %x, want %x", tt.mode, got, in)
+		}
+	}
+
+	block, _ := aes.NewCipher(testKey)
+	for _, tt := range []struct {
+		mode string
+		iv   []byte
+	}{
+		{"xts", testKey},
+		{"cbc", nil},
+		{"ctr", testKey[:8]},
+	} {
+		if _, _, err := NewMode(block, tt.mode, tt.iv, false); err == nil {
+			t.Errorf("NewMode(%s) with a %d-byte IV succeeded", tt.mode, len(tt.iv))
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Package registry names block ciphers in modes of operation as OpenSSL
+// does, such as "aes-256-ctr" or "des-ede3-cbc".
+package registry
+
+import (
+	"crypto/cipher"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/AirWSW/go-crypto/aes"
+	"github.com/AirWSW/go-crypto/des"
+)
+
+// A Cipher is a block cipher in a mode of operation under a name as used by
+// OpenSSL, such as "aes-256-ctr" or "des-ede3-cbc".
+//
+// Exactly one of NewStream and NewBlockMode is set, for modes that encrypt
+// any number of bytes and for modes that encrypt whole blocks, which are
+// left to the caller to pad. Both return an error if key or iv is not of
+// the registered size.
+type Cipher struct {
+	Name      string
+	KeySize   int
+	IVSize    int // 0 if the mode takes no IV
+	BlockSize int
+
+	NewStream    func(key, iv []byte, decrypt bool) (cipher.Stream, error)
+	NewBlockMode func(key, iv []byte, decrypt bool) (cipher.BlockMode, error)
+}
+
+var (
+	ciphersMu sync.RWMutex
+	ciphers   = make(map[string]*Cipher)
+)
+
+// RegisterCipher makes c available under c.Name, which is not case
+// sensitive. It panics if the name is taken or c is malformed.
+func RegisterCipher(c *Cipher) {
+	if c.Name == "" || (c.NewStream == nil) == (c.NewBlockMode == nil) {
+		panic("malformed cipher " + c.Name)
+	}
+	name := strings.ToLower(c.Name)
+	ciphersMu.Lock()
+	defer ciphersMu.Unlock()
+	if _, ok := ciphers[name]; ok {
+		panic("cipher " + name + " registered twice")
+	}
+	ciphers[name] = c
+}
+
+// RegisterBlockCipher registers the block cipher returned by newBlock for
+// keys of keySize bytes in each mode of operation of this module, as
+// name-ecb, name-cbc, name-cfb, name-cfb8, name-ofb and name-ctr. CFB
+// without a suffix feeds back whole blocks.
+func RegisterBlockCipher(name string, keySize int, newBlock func(key []byte) (cipher.Block, error)) {
+	block, err := newBlock(make([]byte, keySize))
+	if err != nil {
+		panic("cipher " + name + ": " + err.Error())
+	}
+	bs := block.BlockSize()
+	for _, m := range []string{"ecb", "cbc", "cfb", "cfb8", "ofb", "ctr"} {
+		mode := m
+		c := &Cipher{Name: name + "-" + m, KeySize: keySize, IVSize: bs, BlockSize: bs}
+		switch m {
+		case "ecb":
+			c.IVSize = 0
+			fallthrough
+		case "cbc":
+			c.NewBlockMode = func(key, iv []byte, decrypt bool) (cipher.BlockMode, error) {
+				block, err := newModeBlock(c, newBlock, key, iv)
+				if err != nil {
+					return nil, err
+				}
+				bm, _, err := NewMode(block, mode, iv, decrypt)
+				return bm, err
+			}
+		default:
+			c.NewStream = func(key, iv []byte, decrypt bool) (cipher.Stream, error) {
+				block, err := newModeBlock(c, newBlock, key, iv)
+				if err != nil {
+					return nil, err
+				}
+				_, s, err := NewMode(block, mode, iv, decrypt)
+				return s, err
+			}
+		}
+		RegisterCipher(c)
+	}
+}
+
+// newModeBlock checks the sizes of key and iv for c and returns the block
+// cipher keyed with key.
+func newModeBlock(c *Cipher, newBlock func([]byte) (cipher.Block, error), key, iv []byte) (cipher.Block, error) {
+	if len(key) != c.KeySize {
+		return nil, fmt.Errorf("%s: key must be %d bytes", c.Name, c.KeySize)
+	}
+	if len(iv) != c.IVSize {
+		return nil, fmt.Errorf("%s: IV must be %d bytes", c.Name, c.IVSize)
+	}
+	return newBlock(key)
+}
+
+// LookupCipher returns the cipher registered under name, which is not case
+// sensitive.
+func LookupCipher(name string) (*Cipher, error) {
+	ciphersMu.RLock()
+	defer ciphersMu.RUnlock()
+	c, ok := ciphers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown cipher %q", name)
+	}
+	return c, nil
+}
+
+// CipherNames returns the sorted names of the registered ciphers.
+func CipherNames() []string {
+	ciphersMu.RLock()
+	defer ciphersMu.RUnlock()
+	names := make([]string, 0, len(ciphers))
+	for name := range ciphers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterBlockCipher("aes-128", 16, aes.NewCipher)
+	RegisterBlockCipher("aes-192", 24, aes.NewCipher)
+	RegisterBlockCipher("aes-256", 32, aes.NewCipher)
+	RegisterBlockCipher("des", 8, des.NewCipher)
+	// Two-key 3DES uses the first key again as the third.
+	RegisterBlockCipher("des-ede", 16, func(key []byte) (cipher.Block, error) {
+		if len(key) != 16 {
+			return nil, fmt.Errorf("invalid key size")
+		}
+		return des.NewTripleDESCipher(append(append([]byte(nil), key...), key[:8]...))
+	})
+	RegisterBlockCipher("des-ede3", 24, des.NewTripleDESCipher)
+}
@@ -0,0 +1,128 @@
+package registry
+
+import (
+	"bytes"
+	stdaes "crypto/aes"
+	"crypto/cipher"
+	stddes "crypto/des"
+	"strings"
+	"testing"
+)
+
+type noopBlock int
+
+func (b noopBlock) BlockSize() int        { return int(b) }
+func (noopBlock) Encrypt(dst, src []byte) { copy(dst, src) }
+func (noopBlock) Decrypt(dst, src []byte) { copy(dst, src) }
+
+var (
+	testKey24 = hexBytes("8e73b0f7da0e6452c810f32b809079e562f8ead2522c6b7b")
+	testKey32 = hexBytes("603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4")
+	testIV    = hexBytes("f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff")
+)
+
+var registryTests = []struct {
+	name  string
+	key   []byte
+	iv    []byte
+	block func(key []byte) (cipher.Block, error)
+}{
+	{"aes-128-ctr", testKey, testIV, stdaes.NewCipher},
+	{"AES-192-CBC", testKey24, testIV, stdaes.NewCipher},
+	{"aes-256-cfb", testKey32, testIV, stdaes.NewCipher},
+	{"aes-256-ecb", testKey32, nil, stdaes.NewCipher},
+	{"des-ofb", testKey[:8], testIV[:8], stddes.NewCipher},
+	{"des-ede3-cbc", testKey24, testIV[:8], stddes.NewTripleDESCipher},
+	{"des-ede-cfb8", testKey, testIV[:8], func(key []byte) (cipher.Block, error) {
+		return stddes.NewTripleDESCipher(append(append([]byte(nil), key...), key[:8]...))
+	}},
+}
+
+func Test_LookupCipher(t *testing.T) {
+	for _, tt := range registryTests {
+		c, err := LookupCipher(tt.name)
+		if err != nil {
+			t.Fatalf("%s: LookupCipher() = %s", tt.name, err)
+		}
+		if c.KeySize != len(tt.key) || c.IVSize != len(tt.iv) {
+			t.Errorf("%s: KeySize, IVSize = %d, %d, want %d, %d", tt.name, c.KeySize, c.IVSize, len(tt.key), len(tt.iv))
+		}
+		block, _ := tt.block(tt.key)
+		mode := strings.ToLower(tt.name[strings.LastIndexByte(tt.name, '-')+1:])
+		want := make([]byte, len(testInput))
+		bm, stream, err := NewMode(block, mode, tt.iv, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bm != nil {
+			bm.CryptBlocks(want, testInput)
+		} else {
+			stream.XORKeyStream(want, testInput)
+		}
+		got := make([]byte, len(testInput))
+		pt := make([]byte, len(testInput))
+		if c.NewStream != nil {
+			enc, err := c.NewStream(tt.key, tt.iv, false)
+			if err != nil {
+				t.Fatalf("%s: NewStream() = %s", tt.name, err)
+			}
+			enc.XORKeyStream(got, testInput)
+			dec, _ := c.NewStream(tt.key, tt.iv, true)
+			dec.XORKeyStream(pt, got)
+		} else {
+			enc, err := c.NewBlockMode(tt.key, tt.iv, false)
+			if err != nil {
+				t.Fatalf("%s: NewBlockMode() = %s", tt.name, err)
+			}
+			enc.CryptBlocks(got, testInput)
+			dec, _ := c.NewBlockMode(tt.key, tt.iv, true)
+			dec.CryptBlocks(pt, got)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: encrypt = %x, want %x", tt.name, got, want)
+		}
+		if !bytes.Equal(pt, testInput) {
+			t.Errorf("%s: decrypt = %x, want %x", tt.name, pt, testInput)
+		}
+	}
+
+	if _, err := LookupCipher("rot13"); err == nil {
+		t.Errorf("LookupCipher(rot13) succeeded")
+	}
+	c, _ := LookupCipher("aes-128-ctr")
+	if _, err := c.NewStream(testKey32, testIV, false); err == nil {
+		t.Errorf("aes-128-ctr accepted a 32-byte key")
+	}
+	if _, err := c.NewStream(testKey, testIV[:8], false); err == nil {
+		t.Errorf("aes-128-ctr accepted an 8-byte IV")
+	}
+}
+
+func Test_RegisterBlockCipher(t *testing.T) {
+	if _, err := LookupCipher("test-noop-ctr"); err != nil {
+		RegisterBlockCipher("test-noop", 4, func(key []byte) (cipher.Block, error) { return noopBlock(8), nil })
+	}
+	c, err := LookupCipher("test-noop-ctr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.KeySize != 4 || c.IVSize != 8 || c.BlockSize != 8 {
+		t.Errorf("test-noop-ctr sizes = %d, %d, %d, want 4, 8, 8", c.KeySize, c.IVSize, c.BlockSize)
+	}
+	found := 0
+	for _, name := range CipherNames() {
+		if strings.HasPrefix(name, "test-noop-") {
+			found++
+		}
+	}
+	if found != 6 {
+		t.Errorf("CipherNames() lists %d test-noop modes, want 6", found)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("RegisterCipher() of a taken name did not panic")
+		}
+	}()
+	RegisterCipher(&Cipher{Name: "TEST-NOOP-CTR", NewStream: c.NewStream})
+}
@@ -0,0 +1,53 @@
+// ISO/IEC 9797-1:2011: Message Authentication Codes (MACs), Part 1: Mechanisms using a block cipher
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/AirWSW/go-crypto/des"
+)
+
+// RetailMAC returns the ISO 9797-1 MAC algorithm 3 of msg, known as the
+// retail MAC, under the 16-byte key K1 || K2: the DES CBC-MAC under K1,
+// with the last block decrypted under K2 and encrypted under K1 again.
+//
+// With padding method 1, msg is padded with zeros to a multiple of 8
+// bytes, or to 8 bytes if it is empty. With padding method 2, 0x80 is
+// appended before the zeros.
+func RetailMAC(key, msg []byte, padding int) ([]byte, error) {
+	if len(key) != 16 {
+		return nil, fmt.Errorf("invalid key size")
+	}
+	k1, err := des.NewCipher(key[:8])
+	if err != nil {
+		return nil, err
+	}
+	k2, err := des.NewCipher(key[8:])
+	if err != nil {
+		return nil, err
+	}
+	switch padding {
+	case 1:
+		msg = append([]byte(nil), msg...)
+		if len(msg) == 0 {
+			msg = make([]byte, des.BlockSize)
+		}
+	case 2:
+		msg = append(append([]byte(nil), msg...), 0x80)
+	default:
+		return nil, fmt.Errorf("unknown padding method %d", padding)
+	}
+	for len(msg)%des.BlockSize != 0 {
+		msg = append(msg, 0)
+	}
+
+	h := make([]byte, des.BlockSize)
+	for ; len(msg) > 0; msg = msg[des.BlockSize:] {
+		xorBytes(h, h, msg)
+		k1.Encrypt(h, h)
+	}
+	k2.Decrypt(h, h)
+	k1.Encrypt(h, h)
+	return h, nil
+}
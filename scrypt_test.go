@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// RFC 7914, Section 12, without the last vector, which takes 1 GiB.
+var scryptTests = []struct {
+	password, salt string
+	n, r, p        int
+	dk             string
+}{
+	{"", "", 16, 1, 1, "77d6576238657b203b19ca42c18a0497f16b4844e3074ae8dfdffa3fede21442fcd0069ded0948f8326a753a0fc81f17e8d3e0fb2e0d3628cf35e20c38d18906"},
+	{"password", "NaCl", 1024, 8, 16, "fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b3731622eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640"},
+	{"pleaseletmein", "SodiumChloride", 16384, 8, 1, "7023bdcb3afd7348461c06cd81fd38ebfda8fbba904f8e3ea9b543f6545da1f2d5432955613f0fcf62d49705242a9af9e61e85dc0d651e40dfcf017b45575887"},
+}
+
+func Test_scryptKey(t *testing.T) {
+	for i, tt := range scryptTests {
+		want, _ := hex.DecodeString(tt.dk)
+		got, err := scryptKey([]byte(tt.password), []byte(tt.salt), tt.n, tt.r, tt.p, len(want))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("#%d: scryptKey() = %x, %v, want %x", i, got, err, want)
+		}
+	}
+	for _, n := range []int{0, 1, 3, 1000} {
+		if _, err := scryptKey(nil, nil, n, 8, 1, 32); err == nil {
+			t.Errorf("scryptKey() with N = %d succeeded", n)
+		}
+	}
+	if _, err := scryptKey(nil, nil, 16, 1<<20, 1<<10, 32); err == nil {
+		t.Errorf("scryptKey() with r*p = 2^30 succeeded")
+	}
+}
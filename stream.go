@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/cipher"
+	"io"
+)
+
+// StreamReader wraps a Stream into an io.Reader. It calls XORKeyStream to
+// process each slice of data which passes through.
+type StreamReader struct {
+	s cipher.Stream
+	r io.Reader
+}
+
+// NewStreamReader returns a StreamReader which decrypts (or encrypts) the
+// data read from r with s, such as a Stream returned by NewCTR.
+func NewStreamReader(s cipher.Stream, r io.Reader) *StreamReader {
+	return &StreamReader{s: s, r: r}
+}
+
+func (r *StreamReader) Read(dst []byte) (int, error) {
+	n, err := r.r.Read(dst)
+	r.s.XORKeyStream(dst[:n], dst[:n])
+	return n, err
+}
+
+// Close closes the underlying Reader if it implements io.Closer.
+func (r *StreamReader) Close() error {
+	if c, ok := r.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// StreamWriter wraps a Stream into an io.Writer. It calls XORKeyStream to
+// process each slice of data which passes through.
+//
+// The keystream consumed by bytes the underlying Writer did not accept is
+// kept and used for the next Write, so after a short write the caller can
+// retry with the remaining bytes and the output stays aligned with the
+// keystream.
+type StreamWriter struct {
+	s   cipher.Stream
+	w   io.Writer
+	ks  []byte
+	buf []byte
+}
+
+// NewStreamWriter returns a StreamWriter which encrypts (or decrypts) the
+// data written to it with s, such as a Stream returned by NewCTR, and writes
+// the result to w.
+func NewStreamWriter(s cipher.Stream, w io.Writer) *StreamWriter {
+	return &StreamWriter{s: s, w: w}
+}
+
+func (w *StreamWriter) Write(src []byte) (int, error) {
+	if cap(w.buf) < len(src) {
+		w.buf = make([]byte, len(src))
+	}
+	buf := w.buf[:len(src)]
+
+	k := xorBytes(buf, src, w.ks)
+	w.ks = w.ks[k:]
+	w.s.XORKeyStream(buf[k:], src[k:])
+
+	n := 0
+	var err error
+	for n < len(buf) && err == nil {
+		var m int
+		m, err = w.w.Write(buf[n:])
+		if m == 0 && err == nil {
+			err = io.ErrShortWrite
+		}
+		n += m
+	}
+	if n < len(buf) {
+		// Recover the keystream of the unwritten bytes, ahead of any
+		// keystream still left over from an earlier short write.
+		ks := make([]byte, len(buf)-n+len(w.ks))
+		xorBytes(ks, buf[n:], src[n:])
+		copy(ks[len(buf)-n:], w.ks)
+		w.ks = ks
+	}
+	return n, err
+}
+
+// Close closes the underlying Writer if it implements io.Closer.
+func (w *StreamWriter) Close() error {
+	if c, ok := w.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
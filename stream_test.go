@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func ctrAES128(in []byte) []byte {
+	out := make([]byte, len(in))
+	NewCTR(aesBlock(commonKey128), commonCounter).XORKeyStream(out, in)
+	return out
+}
+
+func Test_StreamReader_Read(t *testing.T) {
+	want := ctrAES128(commonInput)
+	readers := map[string]func(io.Reader) io.Reader{
+		"whole":    func(r io.Reader) io.Reader { return r },
+		"onebyte":  iotest.OneByteReader,
+		"half":     iotest.HalfReader,
+		"dataerr":  iotest.DataErrReader,
+		"timeout":  func(r io.Reader) io.Reader { return iotest.TimeoutReader(iotest.OneByteReader(r)) },
+		"multiple": func(r io.Reader) io.Reader { return io.MultiReader(iotest.HalfReader(r), r) },
+	}
+	for name, wrap := range readers {
+		r := NewStreamReader(NewCTR(aesBlock(commonKey128), commonCounter), wrap(bytes.NewReader(want)))
+		var got []byte
+		buf := make([]byte, 7)
+		for {
+			n, err := r.Read(buf)
+			got = append(got, buf[:n]...)
+			if err == io.EOF {
+				break
+			}
+			if err != nil && err != iotest.ErrTimeout {
+				t.Fatalf("%s: Read() = %s", name, err)
+			}
+		}
+		if !bytes.Equal(got, commonInput) {
+			t.Errorf("%s: StreamReader\nhave %x\nwant %x", name, got, commonInput)
+		}
+	}
+}
+
+// shortWriter accepts at most n bytes per call, returning err after a short
+// write.
+type shortWriter struct {
+	bytes.Buffer
+	n      int
+	err    error
+	closed int
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if len(p) <= w.n {
+		return w.Buffer.Write(p)
+	}
+	m, _ := w.Buffer.Write(p[:w.n])
+	return m, w.err
+}
+
+func (w *shortWriter) Close() error {
+	w.closed++
+	return nil
+}
+
+func Test_StreamWriter_Write(t *testing.T) {
+	want := ctrAES128(commonInput)
+
+	// A writer that accepts a few bytes at a time without an error is
+	// called until it has taken everything.
+	sw := &shortWriter{n: 5}
+	w := NewStreamWriter(NewCTR(aesBlock(commonKey128), commonCounter), sw)
+	if n, err := w.Write(commonInput); n != len(commonInput) || err != nil {
+		t.Fatalf("Write() = %d, %v, want %d, nil", n, err, len(commonInput))
+	}
+	if !bytes.Equal(sw.Bytes(), want) {
+		t.Errorf("StreamWriter\nhave %x\nwant %x", sw.Bytes(), want)
+	}
+
+	// After a short write with an error the caller retries with the rest.
+	errBusy := errors.New("busy")
+	for _, chunk := range []int{1, 7, 16, 33} {
+		sw := &shortWriter{n: 3, err: errBusy}
+		w := NewStreamWriter(NewCTR(aesBlock(commonKey128), commonCounter), sw)
+		for in := commonInput; len(in) > 0; {
+			m := chunk
+			if m > len(in) {
+				m = len(in)
+			}
+			n, err := w.Write(in[:m])
+			if n < m && err != errBusy {
+				t.Fatalf("%d: Write() = %d, %v, want error %v", chunk, n, err, errBusy)
+			}
+			in = in[n:]
+		}
+		if !bytes.Equal(sw.Bytes(), want) {
+			t.Errorf("%d: StreamWriter\nhave %x\nwant %x", chunk, sw.Bytes(), want)
+		}
+	}
+
+	// A writer that makes no progress fails instead of looping.
+	sw = &shortWriter{n: 0}
+	w = NewStreamWriter(NewCTR(aesBlock(commonKey128), commonCounter), sw)
+	if n, err := w.Write(commonInput); n != 0 || err != io.ErrShortWrite {
+		t.Errorf("Write() = %d, %v, want 0, %v", n, err, io.ErrShortWrite)
+	}
+}
+
+func Test_Stream_Close(t *testing.T) {
+	sw := &shortWriter{n: 64}
+	w := NewStreamWriter(NewCTR(aesBlock(commonKey128), commonCounter), sw)
+	if err := w.Close(); err != nil || sw.closed != 1 {
+		t.Errorf("StreamWriter.Close() = %v, closed %d times", err, sw.closed)
+	}
+	w = NewStreamWriter(NewCTR(aesBlock(commonKey128), commonCounter), io.Discard)
+	if err := w.Close(); err != nil {
+		t.Errorf("StreamWriter.Close() = %v", err)
+	}
+
+	pr, pw := io.Pipe()
+	r := NewStreamReader(NewCTR(aesBlock(commonKey128), commonCounter), pr)
+	if err := r.Close(); err != nil {
+		t.Errorf("StreamReader.Close() = %v", err)
+	}
+	if _, err := pw.Write([]byte{0}); err != io.ErrClosedPipe {
+		t.Errorf("write to closed pipe = %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+// Test_Stream_Pipe encrypts through a StreamWriter and decrypts through a
+// StreamReader connected by a pipe.
+func Test_Stream_Pipe(t *testing.T) {
+	pr, pw := io.Pipe()
+	go func() {
+		w := NewStreamWriter(NewCTR(aesBlock(commonKey256), commonCounter), pw)
+		for i := 0; i < len(commonInput); i += 10 {
+			end := i + 10
+			if end > len(commonInput) {
+				end = len(commonInput)
+			}
+			w.Write(commonInput[i:end])
+		}
+		w.Close()
+	}()
+	got, err := io.ReadAll(NewStreamReader(NewCTR(aesBlock(commonKey256), commonCounter), pr))
+	if err != nil || !bytes.Equal(got, commonInput) {
+		t.Errorf("pipe = %x, %v, want %x", got, err, commonInput)
+	}
+}
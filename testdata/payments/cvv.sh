@@ -0,0 +1,51 @@
+#!/bin/bash
+# Recomputes with openssl the CVVs and PVVs of cvv_test.go, by the Visa
+# methods: for the CVV, PAN || expiry || service code padded to two blocks,
+# the first encrypted under CVK A, XORed with the second and encrypted under
+# CVK A || CVK B; for the PVV, the 11 rightmost digits of the PAN but the
+# check digit || PVKI || 4 PIN digits encrypted under the PVK:
+#
+#	bash testdata/payments/cvv.sh
+
+. "$(dirname "$0")/lib.sh"
+
+# decimalize hex n dectab is the first n digits of hex: with dectab, its
+# nibbles mapped through the table; without, its decimal digits, then its
+# other nibbles minus 10.
+decimalize() {
+	if [ -n "$3" ]; then
+		d=
+		i=0
+		while [ $i -lt ${#1} ]; do
+			d=$d${3:$((0x${1:$i:1})):1}
+			i=$((i + 1))
+		done
+	else
+		d=$(printf '%s' "$1" | tr -d a-f)$(printf '%s' "$1" | tr -d 0-9 | tr a-f 0-5)
+	fi
+	printf '%s\n' "${d:0:$2}"
+}
+
+key=0123456789abcdeffedcba9876543210
+
+echo "# Test_CVV: pan expiry service-code dectab cvv"
+for v in "4123456789012345 8701 101" "4123456789012345 8701 000" "4123456789012345 8701 999" \
+	"5123456789012346 2512 201" "4999988887777 9912 101" "5413330089020011 2612 000" \
+	"4123456789012345 8701 000 0123456789012345" "4999988887777000 9912 101 0123456789012345" \
+	"5413330089020011 2612 000 0123456789012345"; do
+	set -- $v
+	data=$1$2$3
+	while [ ${#data} -lt 32 ]; do data=${data}0; done
+	b=$(des ${key:0:16} ${data:0:16})
+	b=$(tdes $key "$(xor "$b" ${data:16:16})")
+	echo "$1 $2 $3 ${4:-''} $(decimalize "$b" 3 "$4")"
+done
+
+echo "# Test_PVV: pan pvki pin dectab pvv"
+for v in "4123456789012345 1 1234" "4123456789012345 1 12345678" "4003000123456781 1 1234" \
+	"5413330089020011 6 9876" "4999988887777000 0 0000" "4123456789012345 1 1216" \
+	"4123456789012345 1 1234 9876543210987654" "5413330089020011 6 9876 9876543210987654"; do
+	set -- $v
+	tsp=${1: -12:11}$2${3:0:4}
+	echo "$1 $2 $3 ${4:-''} $(decimalize "$(tdes $key $tsp)" 4 "$4")"
+done
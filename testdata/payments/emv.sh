@@ -0,0 +1,83 @@
+#!/bin/bash
+# Recomputes with openssl the expected values of emv_test.go, from EMV 4.4
+# Book 2 Annex A1.4 (ICC master keys), A1.3 (common session key) and 8.1-8.2
+# (ARQC and ARPC), and of retailmac_test.go, from ISO/IEC 9797-1 MAC
+# algorithm 3:
+#
+#	bash testdata/payments/emv.sh
+
+. "$(dirname "$0")/lib.sh"
+
+# parity key sets the low bit of each byte of key for odd parity.
+parity() {
+	out=
+	i=0
+	while [ $i -lt ${#1} ]; do
+		b=$((0x${1:$i:2}))
+		n=0
+		for j in 1 2 3 4 5 6 7; do n=$((n + (b >> j & 1))); done
+		out=$out$(printf '%02x' $((b & 0xfe | (n + 1) % 2)))
+		i=$((i + 2))
+	done
+	printf '%s\n' "$out"
+}
+
+# master imk y is the ICC master key from the 16 digits y.
+master() {
+	parity "$(tdes "$1" "$2")$(tdes "$1" "$(xor "$2" ffffffffffffffff)")"
+}
+
+# option_a imk pan psn
+option_a() {
+	y=$2${3:-00}
+	y=$(printf '%016s' "${y: -16}" | tr ' ' 0)
+	master "$1" "$y"
+}
+
+# option_b imk pan psn falls back to option A for PAN || PSN of up to 16
+# digits.
+option_b() {
+	x=$2${3:-00}
+	if [ ${#x} -le 16 ]; then
+		option_a "$1" "$2" "$3"
+		return
+	fi
+	if [ $((${#x} % 2)) -ne 0 ]; then x=0$x; fi
+	h=$(printf '%s' "$x" | xxd -r -p | openssl dgst -sha1 -binary | xxd -p -c 256)
+	y=$(printf '%s' "$h" | tr -d a-f)$(printf '%s' "$h" | tr -d 0-9 | tr a-f 0-5)
+	master "$1" "${y:0:16}"
+}
+
+# session mk r is the common session key for the diversification value r.
+session() {
+	printf '%s%s\n' "$(tdes "$1" "${2:0:4}f0${2:6:10}")" "$(tdes "$1" "${2:0:4}0f${2:6:10}")"
+}
+
+imk=0123456789abcdeffedcba9876543210
+echo "# Test_EMVICCMasterKey: pan psn option-a option-b"
+for v in "4000000000000002 01" "5413330089020011 ''" "12345678901234567 45" "1234567890123456789 00" "36000000000006 00"; do
+	set -- $v
+	psn=${2//\'/}
+	echo "$1 ${psn:-''} $(option_a $imk $1 "$psn") $(option_b $imk $1 "$psn")"
+done
+
+echo "# Test_EMV_ARQC"
+mk=$(option_a $imk 5413330089020011 00)
+sk=$(session "$mk" 0001000000000000)
+echo "session key $sk"
+data=0000000010000000000000000840000000000008402301019900000001005c00000103a4a000
+arqc=$(retailmac "$sk" "$data" 2)
+echo "arqc $arqc"
+echo "arpc method 1 $(tdes "$sk" "$(xor "$arqc" "$(ascii 00)000000000000")")"
+for prop in "" 1122334455; do
+	mac=$(retailmac "$sk" "${arqc}00820000$prop" 2)
+	echo "arpc method 2 ${prop:-(none)} ${mac:0:8}"
+done
+
+echo "# Test_RetailMAC: message padding mac"
+key=0123456789abcdeffedcba9876543210
+for v in "Now is the time for all |1" "Now is the time for it|2" "|1" "|2"; do
+	msg=${v%|*}
+	pad=${v##*|}
+	echo "\"$msg\" $pad $(retailmac $key "$(ascii "$msg")" $pad)"
+done
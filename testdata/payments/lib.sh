@@ -0,0 +1,56 @@
+# Helpers for the scripts in this directory, which recompute the expected
+# values of the payment tests with the openssl(1) command line tool (3.0 or
+# later) and xxd(1) instead of this module. All values are hex strings.
+
+# ecb cipher key data [-d] encrypts or decrypts data with an openssl enc
+# cipher in ECB mode without padding.
+ecb() {
+	printf '%s' "$3" | xxd -r -p | openssl enc "-$1" -K "$2" -nopad $4 | xxd -p -c 256
+}
+
+# des key data [-d] is single DES: 2-key 3DES with K || K, because OpenSSL 3
+# keeps DES-ECB in the legacy provider.
+des() { ecb des-ede-ecb "$1$1" "$2" $3; }
+
+# tdes key data [-d] is 3DES with a 16 or 24-byte key.
+tdes() {
+	if [ ${#1} -eq 32 ]; then ecb des-ede-ecb "$1" "$2" $3; else ecb des-ede3-ecb "$1" "$2" $3; fi
+}
+
+# cmac cipher key data is the CMAC of data under key, with cipher
+# DES-EDE-CBC, DES-EDE3-CBC or AES-n-CBC.
+cmac() {
+	printf '%s' "$3" | xxd -r -p | openssl mac -cipher "$1" -macopt "hexkey:$2" -in /dev/stdin CMAC | tr A-F a-f
+}
+
+# xor a b is a XOR b, which are of the same length.
+xor() {
+	out=
+	i=0
+	while [ $i -lt ${#1} ]; do
+		out=$out$(printf '%02x' $((0x${1:$i:2} ^ 0x${2:$i:2})))
+		i=$((i + 2))
+	done
+	printf '%s\n' "$out"
+}
+
+# ascii s is the hex of the bytes of s.
+ascii() { printf '%s' "$1" | xxd -p -c 256; }
+
+# retailmac key data padding is the ISO 9797-1 MAC algorithm 3 of data
+# under the 16-byte key K1 || K2, with padding method 1 or 2.
+retailmac() {
+	k1=${1:0:16}
+	k2=${1:16:16}
+	m=$2
+	if [ "$3" = 2 ]; then m=${m}80; fi
+	if [ -z "$m" ]; then m=0000000000000000; fi
+	while [ $((${#m} % 16)) -ne 0 ]; do m=${m}00; done
+	h=0000000000000000
+	i=0
+	while [ $i -lt ${#m} ]; do
+		h=$(des "$k1" "$(xor "$h" "${m:$i:16}")")
+		i=$((i + 16))
+	done
+	des "$k1" "$(des "$k2" "$h" -d)"
+}
@@ -0,0 +1,70 @@
+#!/bin/bash
+# Recomputes with openssl the key blocks of tr31_test.go from TR-31-2018
+# (ANSI X9.143): the KBEK and KBMK are derived with the CMAC of
+#
+#	counter || key usage (0000 encryption, 0001 MAC) || 00 ||
+#	algorithm (0000 2-key 3DES, 0001 3-key 3DES, 0002-0004 AES) || bits
+#
+# and the key block is the header, the key data encrypted in CBC mode with
+# the MAC as IV, and the CMAC of the header and the clear key data:
+#
+#	bash testdata/payments/tr31.sh
+
+. "$(dirname "$0")/lib.sh"
+
+# derive version kbpk usage prints the KBEK (usage 0000) or KBMK (0001).
+derive() {
+	n=${#2}
+	case $1$n in
+	B32) alg=0000 c=DES-EDE-CBC ;;
+	B48) alg=0001 c=DES-EDE3-CBC ;;
+	D32) alg=0002 c=AES-128-CBC ;;
+	D48) alg=0003 c=AES-192-CBC ;;
+	D64) alg=0004 c=AES-256-CBC ;;
+	esac
+	bits=$(printf '%04x' $((4 * n)))
+	k=
+	i=1
+	while [ ${#k} -lt $n ]; do
+		k=$k$(cmac $c "$2" "$(printf '%02x' $i)${3}00$alg$bits")
+		i=$((i + 1))
+	done
+	printf '%s\n' "${k:0:$n}"
+}
+
+# wrap kbpk header key padding, where the key block length in the header
+# is 0000.
+wrap() {
+	v=${2:0:1}
+	if [ "$v" = B ]; then
+		c=DES-EDE-CBC bs=16 enc=des-ede-cbc
+		if [ ${#1} -eq 48 ]; then c=DES-EDE3-CBC enc=des-ede3-cbc; fi
+	else
+		c=AES-$((4 * ${#1}))-CBC bs=32 enc=aes-$((4 * ${#1}))-cbc
+	fi
+	kbek=$(derive $v "$1" 0000)
+	kbmk=$(derive $v "$1" 0001)
+	clear=$(printf '%04x' $((4 * ${#3})))$3$4
+	h=$v$(printf '%04d' $((${#2} + ${#clear} + bs)))${2:5}
+	mac=$(cmac $c "$kbmk" "$(ascii "$h")$clear")
+	ct=$(printf '%s' "$clear" | xxd -r -p | openssl enc -$enc -K "$kbek" -iv "$mac" -nopad | xxd -p -c 1024)
+	printf '%s%s%s\n' "$h" "$ct" "$mac" | tr a-f A-F
+}
+
+x300=$(printf 'x%.0s' $(seq 300))
+wrap 89e88cf7931444f334bd7547fc3f380c \
+	B0000P0TE00N0000 \
+	edb380dd340bc2620247d445f5b8d678 a1a2a3a4a5a6
+wrap 0123456789abcdeffedcba98765432100011223344556677 \
+	B0000K0TB01E0100KS1800604B120F9292800000 \
+	f1f2f3f4f5f6f7f8f9fafbfcfdfef0f1e1e2e3e4e5e6e7e8 b1b2b3b4b5b6
+wrap 88e1ab2a2e3dd38c1fa039a536500cc8a87ab9d62dc92c01058fa79f44657de6 \
+	D0000B0AN00S0200KS1800604B120F9292800000PB080000 \
+	000102030405060708090a0b0c0d0e0f c1c2c3c4c5c6c7c8c9cacbcccdce
+wrap 00112233445566778899aabbccddeeff0011223344556677 \
+	D0000M3AC00E02001000040136${x300}PB0A000000 \
+	000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f d1d2d3d4d5d6d7d8d9dadbdcddde
+# TR-31-2018 Annex A.7.4.
+wrap 88e1ab2a2e3dd38c1fa039a536500cc8a87ab9d62dc92c01058fa79f44657de6 \
+	D0000P0AE00E0000 \
+	3f419e1cb7079442aa37474c2efbf8b8 1c2965473ce206bb855b01533782
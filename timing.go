@@ -0,0 +1,222 @@
+// Dude, is my code constant time?
+// https://eprint.iacr.org/2016/1123
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Thresholds on |t| from dudect: above timingLeak the two classes are
+// distinguishable with near certainty, above timingMaybeLeak probably.
+const (
+	timingLeak      = 10
+	timingMaybeLeak = 4.5
+)
+
+const (
+	timingBatch = 10000
+	timingCrops = 10
+)
+
+// welchTest accumulates the running mean and variance of the measurements of
+// two classes, using Welford's method, for Welch's t-test.
+type welchTest struct {
+	n, mean, m2 [2]float64
+}
+
+func (w *welchTest) push(class int, x float64) {
+	w.n[class]++
+	d := x - w.mean[class]
+	w.mean[class] += d / w.n[class]
+	w.m2[class] += d * (x - w.mean[class])
+}
+
+// t returns Welch's t statistic, or 0 until each class has two measurements.
+func (w *welchTest) t() float64 {
+	if w.n[0] < 2 || w.n[1] < 2 {
+		return 0
+	}
+	v0 := w.m2[0] / (w.n[0] - 1)
+	v1 := w.m2[1] / (w.n[1] - 1)
+	se := math.Sqrt(v0/w.n[0] + v1/w.n[1])
+	if se == 0 {
+		return 0
+	}
+	return (w.mean[0] - w.mean[1]) / se
+}
+
+// timingTarget is a function whose running time should not depend on its
+// input of size bytes.
+type timingTarget struct {
+	name string
+	size int
+	f    func(dst, src []byte)
+}
+
+// timingTargets returns the Encrypt and Decrypt methods of each cipher under
+// a random key.
+func timingTargets(rnd *rand.Rand) ([]timingTarget, error) {
+	var targets []timingTarget
+	for _, c := range []struct {
+		name string
+		alg  string
+		key  int
+	}{
+		{"aes-128", "aes", 16}, {"aes-192", "aes", 24}, {"aes-256", "aes", 32},
+		{"des", "des", 8}, {"3des", "3des", 24},
+	} {
+		key := make([]byte, c.key)
+		rnd.Read(key)
+		block, err := newBlock(c.alg, key)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets,
+			timingTarget{c.name + "-encrypt", block.BlockSize(), block.Encrypt},
+			timingTarget{c.name + "-decrypt", block.BlockSize(), block.Decrypt})
+	}
+	return targets, nil
+}
+
+// timingResult is the outcome of measureTiming: the number of measurements
+// and the largest |t| over the uncropped and cropped tests.
+type timingResult struct {
+	n int
+	t float64
+}
+
+func (r timingResult) verdict() string {
+	switch {
+	case r.t > timingLeak:
+		return "LEAK"
+	case r.t > timingMaybeLeak:
+		return "maybe"
+	}
+	return "ok"
+}
+
+// measureTiming times n calls of target.f, each on either a fixed all-zero
+// input or a random one, chosen at random, and compares the two classes of
+// running times with Welch's t-test. Inputs are prepared in batches ahead of
+// the measurements. The first batch is a warm-up that only sets the
+// thresholds of the cropped tests, which drop the measurements above a
+// percentile to weaken the long tail of interrupts and preemption.
+//
+// A large |t| means that the running time depends on the input, such as
+// through the data-dependent branches of gmul in the aes package. A small
+// one is no proof of the contrary.
+func measureTiming(target timingTarget, n int, rnd *rand.Rand) timingResult {
+	classes := make([]int, timingBatch)
+	inputs := make([]byte, timingBatch*target.size)
+	times := make([]float64, timingBatch)
+	dst := make([]byte, target.size)
+
+	var crops []float64
+	tests := make([]welchTest, 1+timingCrops)
+	for done := -timingBatch; done < n; done += timingBatch {
+		for i := range classes {
+			classes[i] = rnd.Intn(2)
+			in := inputs[i*target.size : (i+1)*target.size]
+			if classes[i] == 0 {
+				for j := range in {
+					in[j] = 0
+				}
+			} else {
+				rnd.Read(in)
+			}
+		}
+		for i := range times {
+			in := inputs[i*target.size : (i+1)*target.size]
+			start := time.Now()
+			target.f(dst, in)
+			times[i] = float64(time.Since(start))
+		}
+
+		if crops == nil {
+			sorted := append([]float64(nil), times...)
+			sort.Float64s(sorted)
+			for i := 0; i < timingCrops; i++ {
+				p := 1 - math.Pow(0.5, 10*float64(i+1)/timingCrops)
+				crops = append(crops, sorted[int(p*float64(len(sorted)-1))])
+			}
+			continue
+		}
+		for i, x := range times {
+			tests[0].push(classes[i], x)
+			for j, c := range crops {
+				if x <= c {
+					tests[1+j].push(classes[i], x)
+				}
+			}
+		}
+	}
+
+	r := timingResult{n: int(tests[0].n[0] + tests[0].n[1])}
+	for i := range tests {
+		r.t = math.Max(r.t, math.Abs(tests[i].t()))
+	}
+	return r
+}
+
+func timingCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("timing", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: go-crypto timing [-n measurements] [-seed n] [target ...]\n")
+		fs.PrintDefaults()
+	}
+	n := fs.Int("n", 100000, "number of measurements per target")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "seed of the keys, inputs and classes")
+	list := fs.Bool("l", false, "list the targets")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	targets, err := timingTargets(rand.New(rand.NewSource(*seed)))
+	if err != nil {
+		return err
+	}
+	if *list {
+		for _, t := range targets {
+			fmt.Fprintln(stdout, t.name)
+		}
+		return nil
+	}
+	if fs.NArg() > 0 {
+		var chosen []timingTarget
+		for _, name := range fs.Args() {
+			found := false
+			for _, t := range targets {
+				if t.name == name {
+					chosen = append(chosen, t)
+					found = true
+				}
+			}
+			if !found {
+				return fmt.Errorf("unknown target %q", name)
+			}
+		}
+		targets = chosen
+	}
+
+	leaks := 0
+	fmt.Fprintf(stdout, "%-16s %12s %8s  %s\n", "target", "measurements", "max |t|", "verdict")
+	for _, t := range targets {
+		r := measureTiming(t, *n, rand.New(rand.NewSource(*seed)))
+		fmt.Fprintf(stdout, "%-16s %12d %8.2f  %s\n", t.name, r.n, r.t, r.verdict())
+		if r.t > timingLeak {
+			leaks++
+		}
+	}
+	if leaks > 0 {
+		return fmt.Errorf("%d targets leak timing", leaks)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+var timingN = flag.Int("timing", 0, "number of measurements per target for Test_timing_ciphers, which is skipped if 0")
+
+func Test_welchTest_t(t *testing.T) {
+	var w welchTest
+	for _, x := range []float64{1, 2, 3, 4} {
+		w.push(0, x)
+		w.push(1, 2*x)
+	}
+	if got, want := w.t(), -math.Sqrt(3); math.Abs(got-want) > 1e-9 {
+		t.Errorf("welchTest.t() = %v, want %v", got, want)
+	}
+	if got := new(welchTest).t(); got != 0 {
+		t.Errorf("welchTest.t() = %v with no measurements, want 0", got)
+	}
+}
+
+func Test_measureTiming(t *testing.T) {
+	// The fixed input takes the slow branch every time.
+	leaky := timingTarget{"leaky", 16, func(dst, src []byte) {
+		if src[0] == 0 {
+			for i := 0; i < 100; i++ {
+				xorBytes(dst, dst, src)
+			}
+		}
+	}}
+	if r := measureTiming(leaky, 20000, rand.New(rand.NewSource(1))); r.verdict() != "LEAK" {
+		t.Errorf("measureTiming(leaky) = %d, %.2f, want a leak", r.n, r.t)
+	}
+
+	// Whether a constant-time target stays under the threshold depends on
+	// the load of the machine, so it is only checked with -timing.
+	if *timingN == 0 {
+		return
+	}
+	constant := timingTarget{"constant", 16, func(dst, src []byte) { xorBytes(dst, dst, src) }}
+	if r := measureTiming(constant, *timingN, rand.New(rand.NewSource(1))); r.t > timingLeak {
+		t.Errorf("measureTiming(constant) = %d, %.2f, want no leak", r.n, r.t)
+	}
+}
+
+// Test_timing_ciphers reports the ciphers whose running time depends on the
+// input. It takes several seconds per target and is run with
+// go test -run timing_ciphers -timing 100000.
+func Test_timing_ciphers(t *testing.T) {
+	if *timingN == 0 {
+		t.Skip("run with -timing n")
+	}
+	targets, err := timingTargets(rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range targets {
+		r := measureTiming(target, *timingN, rand.New(rand.NewSource(1)))
+		if r.t > timingLeak {
+			t.Errorf("%s: |t| = %.2f over %d measurements", target.name, r.t, r.n)
+		}
+	}
+}
+
+func Test_run_timing(t *testing.T) {
+	out, _, code := runCommand(nil, "timing", "-l")
+	if code != 0 || !strings.Contains(out, "aes-128-encrypt\n") || !strings.Contains(out, "3des-decrypt\n") {
+		t.Errorf("timing -l = %q, %d", out, code)
+	}
+	if _, errOut, code := runCommand(nil, "timing", "rot13"); code != 1 || !strings.Contains(errOut, "unknown target") {
+		t.Errorf("timing rot13 = %q, %d, want exit status 1", errOut, code)
+	}
+}
@@ -0,0 +1,348 @@
+// ANSI X9.143-2022: Retail Financial Services, Interoperable Secure Key Block Specification
+// ASC X9 TR 31-2018: Interoperable Secure Key Exchange Key Block Specification
+
+package main
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/AirWSW/go-crypto/aes"
+	"github.com/AirWSW/go-crypto/des"
+)
+
+// A TR-31 key block is
+//
+//	header || hex(E(KBEK, key length || key || padding)) || hex(MAC)
+//
+// where the header is printable ASCII, and the key length is the number of
+// bits of the key in two bytes. The MAC is the CMAC under KBMK of the
+// header and the clear key data, and is also the IV of the CBC encryption.
+// KBEK and KBMK are derived from the key block protection key with the
+// SP 800-108 counter mode KDF on CMAC.
+const (
+	// TR31VersionB protects key blocks with a 16 or 24-byte 3DES key.
+	TR31VersionB = 'B'
+
+	// TR31VersionD protects key blocks with an AES key.
+	TR31VersionD = 'D'
+
+	tr31HeaderSize = 16
+)
+
+// TR31Header is the header of a TR-31 key block. The fields are printable
+// ASCII, mostly the codes defined by the standard.
+type TR31Header struct {
+	// Version is TR31VersionB or TR31VersionD.
+	Version byte
+
+	// KeyUsage is the use of the key, such as "P0" for PIN encryption or
+	// "K0" for key encryption.
+	KeyUsage string
+
+	// Algorithm is the algorithm of the key, such as 'T' for 3DES or 'A'
+	// for AES.
+	Algorithm byte
+
+	// ModeOfUse restricts the key, such as 'E' for encryption only or 'B'
+	// for both encryption and decryption.
+	ModeOfUse byte
+
+	// KeyVersion is two characters, "00" if the key has no version.
+	KeyVersion string
+
+	// Exportability is 'E' if the key may be exported, 'N' if not and 'S'
+	// if only under a sensitive key.
+	Exportability byte
+
+	// Reserved is the last two characters of the fixed header, "00" if it
+	// is empty. X9.143 uses the first as the key context.
+	Reserved string
+
+	// OptionalBlocks are the optional blocks after the fixed header. The
+	// padding block "PB" is added by TR31Wrap where needed and removed by
+	// TR31Unwrap.
+	OptionalBlocks []TR31OptionalBlock
+}
+
+// TR31OptionalBlock is an optional block of a TR-31 header, such as "KS"
+// with the key set identifier of a DUKPT key.
+type TR31OptionalBlock struct {
+	ID   string
+	Data string
+}
+
+// TR31Wrap returns the key block of key with header h under the key block
+// protection key kbpk. The key block length in the header is filled in and
+// the key is padded with random bytes.
+func TR31Wrap(kbpk []byte, h *TR31Header, key []byte) (string, error) {
+	return tr31Wrap(kbpk, h, key, rand.Reader)
+}
+
+func tr31Wrap(kbpk []byte, h *TR31Header, key []byte, rnd io.Reader) (string, error) {
+	block, kbmk, err := tr31Keys(h.Version, kbpk)
+	if err != nil {
+		return "", err
+	}
+	if len(key) == 0 || len(key) > 0xffff/8 {
+		return "", fmt.Errorf("invalid key length")
+	}
+	bs := block.BlockSize()
+	header, err := h.marshal(bs)
+	if err != nil {
+		return "", err
+	}
+
+	clear := make([]byte, (2+len(key)+bs-1)/bs*bs)
+	clear[0], clear[1] = byte(8*len(key)>>8), byte(8*len(key))
+	copy(clear[2:], key)
+	if _, err := io.ReadFull(rnd, clear[2+len(key):]); err != nil {
+		return "", err
+	}
+	n := len(header) + 2*(len(clear)+bs)
+	if n > 9999 {
+		return "", fmt.Errorf("key block too long")
+	}
+	header = header[:1] + fmt.Sprintf("%04d", n) + header[5:]
+
+	mac := tr31MAC(kbmk, header, clear)
+	cipher.NewCBCEncrypter(block, mac).CryptBlocks(clear, clear)
+	return header + strings.ToUpper(hex.EncodeToString(clear)+hex.EncodeToString(mac)), nil
+}
+
+// TR31Unwrap returns the header and the key of a key block wrapped under
+// kbpk, after checking its MAC.
+func TR31Unwrap(kbpk []byte, keyBlock string) (*TR31Header, []byte, error) {
+	h, n, err := parseTR31Header(keyBlock)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, kbmk, err := tr31Keys(h.Version, kbpk)
+	if err != nil {
+		return nil, nil, err
+	}
+	bs := block.BlockSize()
+	b, err := hex.DecodeString(keyBlock[n:])
+	if err != nil || len(b) < 2*bs || len(b)%bs != 0 {
+		return nil, nil, fmt.Errorf("invalid TR-31 key data")
+	}
+
+	mac := b[len(b)-bs:]
+	clear := make([]byte, len(b)-bs)
+	cipher.NewCBCDecrypter(block, mac).CryptBlocks(clear, b[:len(clear)])
+	if subtle.ConstantTimeCompare(tr31MAC(kbmk, keyBlock[:n], clear), mac) != 1 {
+		return nil, nil, fmt.Errorf("TR-31 key block authentication failed")
+	}
+	bits := int(clear[0])<<8 | int(clear[1])
+	if bits == 0 || bits%8 != 0 || bits/8 > len(clear)-2 {
+		return nil, nil, fmt.Errorf("invalid TR-31 key length")
+	}
+
+	var blocks []TR31OptionalBlock
+	for _, ob := range h.OptionalBlocks {
+		if ob.ID != "PB" {
+			blocks = append(blocks, ob)
+		}
+	}
+	h.OptionalBlocks = blocks
+	return h, clear[2 : 2+bits/8], nil
+}
+
+// tr31Keys returns the cipher of the KBEK and the KBMK derived from kbpk in
+// the given version.
+func tr31Keys(version byte, kbpk []byte) (cipher.Block, []byte, error) {
+	var (
+		prf       = KBKDFAESCMAC
+		algorithm byte
+		newBlock  = aes.NewCipher
+	)
+	switch version {
+	case TR31VersionB:
+		prf, newBlock = KBKDFTDESCMAC, newTR31TDES
+		switch len(kbpk) {
+		case 16:
+			algorithm = 0
+		case 24:
+			algorithm = 1
+		default:
+			return nil, nil, fmt.Errorf("invalid key size")
+		}
+	case TR31VersionD:
+		switch len(kbpk) {
+		case 16, 24, 32:
+			algorithm = byte(len(kbpk) / 8)
+		default:
+			return nil, nil, fmt.Errorf("invalid key size")
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported TR-31 version %q", version)
+	}
+
+	kdf := &KBKDF{PRF: prf, Mode: KBKDFCounter, CounterSize: 1, CounterLocation: KBKDFCounterFirst}
+	var keys [2][]byte
+	for usage := range keys {
+		// The key usage indicator, 0 for encryption and 1 for MAC, and the
+		// algorithm indicator.
+		fixed := KBKDFFixedInput([]byte{0, byte(usage)}, []byte{0, algorithm}, 8*len(kbpk), 2)
+		k, err := kdf.Derive(kbpk, fixed, len(kbpk))
+		if err != nil {
+			return nil, nil, err
+		}
+		keys[usage] = k
+	}
+	block, err := newBlock(keys[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, keys[1], nil
+}
+
+func newTR31TDES(key []byte) (cipher.Block, error) {
+	if len(key) == 16 {
+		return newTwoKeyTDES(key)
+	}
+	return des.NewTripleDESCipher(key)
+}
+
+// tr31MAC returns the CMAC of header || clear under kbmk, with 3DES for
+// version B and AES for version D.
+func tr31MAC(kbmk []byte, header string, clear []byte) []byte {
+	prf := KBKDFAESCMAC
+	if header[0] == TR31VersionB {
+		prf = KBKDFTDESCMAC
+	}
+	m, _ := prf(kbmk)
+	m.Write([]byte(header))
+	m.Write(clear)
+	return m.Sum(nil)
+}
+
+// marshal returns the header with a zero key block length, padded to a
+// multiple of blockSize with a padding block.
+func (h *TR31Header) marshal(blockSize int) (string, error) {
+	fields := []struct {
+		s string
+		n int
+	}{
+		{h.KeyUsage, 2}, {string(h.Algorithm), 1}, {string(h.ModeOfUse), 1},
+		{h.KeyVersion, 2}, {string(h.Exportability), 1},
+	}
+	var b strings.Builder
+	b.WriteByte(h.Version)
+	b.WriteString("0000")
+	for _, f := range fields {
+		if len(f.s) != f.n || !tr31Printable(f.s) {
+			return "", fmt.Errorf("invalid TR-31 header")
+		}
+		b.WriteString(f.s)
+	}
+
+	count := len(h.OptionalBlocks)
+	var opt strings.Builder
+	for _, ob := range h.OptionalBlocks {
+		if len(ob.ID) != 2 || !tr31Printable(ob.ID+ob.Data) || ob.ID == "PB" {
+			return "", fmt.Errorf("invalid TR-31 optional block %q", ob.ID)
+		}
+		opt.WriteString(ob.ID)
+		if n := 4 + len(ob.Data); n <= 0xff {
+			fmt.Fprintf(&opt, "%02X", n)
+		} else if n += 6; n <= 0xffff {
+			fmt.Fprintf(&opt, "0004%04X", n)
+		} else {
+			return "", fmt.Errorf("TR-31 optional block %q too long", ob.ID)
+		}
+		opt.WriteString(ob.Data)
+	}
+	if r := (tr31HeaderSize + opt.Len()) % blockSize; r != 0 {
+		n := blockSize - r
+		if n < 4 {
+			n += blockSize
+		}
+		fmt.Fprintf(&opt, "PB%02X%s", n, strings.Repeat("0", n-4))
+		count++
+	}
+	if count > 99 {
+		return "", fmt.Errorf("too many TR-31 optional blocks")
+	}
+	fmt.Fprintf(&b, "%02d", count)
+
+	reserved := h.Reserved
+	if reserved == "" {
+		reserved = "00"
+	}
+	if len(reserved) != 2 || !tr31Printable(reserved) {
+		return "", fmt.Errorf("invalid TR-31 header")
+	}
+	b.WriteString(reserved)
+	b.WriteString(opt.String())
+	return b.String(), nil
+}
+
+// parseTR31Header parses the header of a key block and returns it with its
+// length, after checking the key block length.
+func parseTR31Header(s string) (*TR31Header, int, error) {
+	errHeader := fmt.Errorf("invalid TR-31 header")
+	if len(s) < tr31HeaderSize || !tr31Printable(s) {
+		return nil, 0, errHeader
+	}
+	if n, err := strconv.Atoi(s[1:5]); err != nil || !isDigits(s[1:5]) || n != len(s) {
+		return nil, 0, fmt.Errorf("TR-31 key block length %q does not match", s[1:5])
+	}
+	count, err := strconv.Atoi(s[12:14])
+	if err != nil || !isDigits(s[12:14]) {
+		return nil, 0, errHeader
+	}
+	h := &TR31Header{
+		Version:       s[0],
+		KeyUsage:      s[5:7],
+		Algorithm:     s[7],
+		ModeOfUse:     s[8],
+		KeyVersion:    s[9:11],
+		Exportability: s[11],
+		Reserved:      s[14:16],
+	}
+
+	p := tr31HeaderSize
+	for i := 0; i < count; i++ {
+		// The length includes the ID and itself. A length of 00 is followed
+		// by the number of digits of the length, then the length.
+		if p+4 > len(s) {
+			return nil, 0, errHeader
+		}
+		start := p
+		n, err := strconv.ParseUint(s[p+2:p+4], 16, 8)
+		p += 4
+		if err == nil && n == 0 {
+			var m uint64
+			if p+2 <= len(s) {
+				m, err = strconv.ParseUint(s[p:p+2], 16, 8)
+			}
+			if p+2 > len(s) || err != nil || m == 0 || m > 4 || p+2+int(m) > len(s) {
+				return nil, 0, errHeader
+			}
+			n, err = strconv.ParseUint(s[p+2:p+2+int(m)], 16, 16)
+			p += 2 + int(m)
+		}
+		if err != nil || start+int(n) < p || start+int(n) > len(s) {
+			return nil, 0, errHeader
+		}
+		h.OptionalBlocks = append(h.OptionalBlocks, TR31OptionalBlock{ID: s[start : start+2], Data: s[p : start+int(n)]})
+		p = start + int(n)
+	}
+	return h, p, nil
+}
+
+func tr31Printable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
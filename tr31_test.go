@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Each key block has the given padding of the key. The last is the example
+// of TR-31-2018 Annex A.7.4. No published version B example was available:
+// all the blocks are recomputed with openssl by testdata/payments/tr31.sh,
+// which also reproduces the Annex A.7.4 block.
+var tr31Tests = []struct {
+	kbpk   string
+	header TR31Header
+	key    string
+	pad    string
+	block  string
+}{
+	{
+		"89e88cf7931444f334bd7547fc3f380c",
+		TR31Header{TR31VersionB, "P0", 'T', 'E', "00", 'N', "00", nil},
+		"edb380dd340bc2620247d445f5b8d678", "a1a2a3a4a5a6",
+		"B0080P0TE00N0000CBA9C83B6C838F0D5EF7B565C98463A2761A7E16BCA197F1335770DE048BB9CB",
+	},
+	{
+		"0123456789abcdeffedcba98765432100011223344556677",
+		TR31Header{TR31VersionB, "K0", 'T', 'B', "01", 'E', "00", []TR31OptionalBlock{{"KS", "00604B120F9292800000"}}},
+		"f1f2f3f4f5f6f7f8f9fafbfcfdfef0f1e1e2e3e4e5e6e7e8", "b1b2b3b4b5b6",
+		"B0120K0TB01E0100KS1800604B120F9292800000D8AB06B6AEA3BDEEFB3A170D42CF4D3F3A11E622AEB3A0358ACE8212DD78B8115EF50F0DFCCB1B4F",
+	},
+	{
+		"88e1ab2a2e3dd38c1fa039a536500cc8a87ab9d62dc92c01058fa79f44657de6",
+		TR31Header{TR31VersionD, "B0", 'A', 'N', "00", 'S', "00", []TR31OptionalBlock{{"KS", "00604B120F9292800000"}}},
+		"000102030405060708090a0b0c0d0e0f", "c1c2c3c4c5c6c7c8c9cacbcccdce",
+		"D0144B0AN00S0200KS1800604B120F9292800000PB080000E7FF019BEC2762A1D00EA33E056B2B035DAE55632EE0D456C05BB536744FAC883BC66FA36778483090CFB0EE8E38D6A1",
+	},
+	{
+		// An optional block with an extended length.
+		"00112233445566778899aabbccddeeff0011223344556677",
+		TR31Header{TR31VersionD, "M3", 'A', 'C', "00", 'E', "00", []TR31OptionalBlock{{"10", strings.Repeat("x", 300)}}},
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "d1d2d3d4d5d6d7d8d9dadbdcddde",
+		"D0464M3AC00E02001000040136" + strings.Repeat("x", 300) + "PB0A000000" +
+			"2F27C323A21539C3887A0F6634C4561DB2B40CCB8E87620551A7BA39C7F068069F91D7DE8A464F0D65A248D32D2F884E9C3C0A9A8BBF03F57D2BB9C224AE5F64",
+	},
+	{
+		"88e1ab2a2e3dd38c1fa039a536500cc8a87ab9d62dc92c01058fa79f44657de6",
+		TR31Header{TR31VersionD, "P0", 'A', 'E', "00", 'E', "00", nil},
+		"3f419e1cb7079442aa37474c2efbf8b8", "1c2965473ce206bb855b01533782",
+		"D0112P0AE00E0000B82679114F470F540165EDFBF7E250FCEA43F810D215F8D207E2E417C07156A27E8E31DA05F7425509593D03A457DC34",
+	},
+}
+
+func Test_tr31Wrap(t *testing.T) {
+	for i, tt := range tr31Tests {
+		kbpk, key := hexBytes(tt.kbpk), hexBytes(tt.key)
+		got, err := tr31Wrap(kbpk, &tt.header, key, bytes.NewReader(hexBytes(tt.pad)))
+		if err != nil {
+			t.Fatalf("#%d: tr31Wrap() = %s", i, err)
+		}
+		if got != tt.block {
+			t.Errorf("#%d: tr31Wrap() = %s, want %s", i, got, tt.block)
+		}
+		h, k, err := TR31Unwrap(kbpk, tt.block)
+		if err != nil {
+			t.Fatalf("#%d: TR31Unwrap() = %s", i, err)
+		}
+		if !reflect.DeepEqual(*h, tt.header) || !bytes.Equal(k, key) {
+			t.Errorf("#%d: TR31Unwrap() = %+v, %x, want %+v, %x", i, *h, k, tt.header, key)
+		}
+	}
+}
+
+func Test_TR31Wrap(t *testing.T) {
+	for i, tt := range tr31Tests {
+		kbpk, key := hexBytes(tt.kbpk), hexBytes(tt.key)
+		b1, err := TR31Wrap(kbpk, &tt.header, key)
+		if err != nil {
+			t.Fatalf("#%d: TR31Wrap() = %s", i, err)
+		}
+		b2, _ := TR31Wrap(kbpk, &tt.header, key)
+		if b1 == b2 || len(b1) != len(tt.block) {
+			t.Errorf("#%d: TR31Wrap() twice = %s, %s", i, b1, b2)
+		}
+		if _, k, err := TR31Unwrap(kbpk, b1); err != nil || !bytes.Equal(k, key) {
+			t.Errorf("#%d: TR31Unwrap() = %x, %v, want %x", i, k, err, key)
+		}
+	}
+}
+
+func Test_TR31Wrap_Invalid(t *testing.T) {
+	kbpk, key := hexBytes(tr31Tests[0].kbpk), hexBytes(tr31Tests[0].key)
+	for i, tt := range []struct {
+		kbpk   []byte
+		header TR31Header
+		key    []byte
+	}{
+		{kbpk, TR31Header{'A', "P0", 'T', 'E', "00", 'N', "", nil}, key},
+		{kbpk[:12], TR31Header{TR31VersionD, "P0", 'A', 'E', "00", 'N', "", nil}, key},
+		{kbpk[:8], TR31Header{TR31VersionB, "P0", 'T', 'E', "00", 'N', "", nil}, key},
+		{kbpk, TR31Header{TR31VersionB, "P", 'T', 'E', "00", 'N', "", nil}, key},
+		{kbpk, TR31Header{TR31VersionB, "P0", 0, 'E', "00", 'N', "", nil}, key},
+		{kbpk, TR31Header{TR31VersionB, "P0", 'T', 'E', "00", 'N', "000", nil}, key},
+		{kbpk, TR31Header{TR31VersionB, "P0", 'T', 'E', "00", 'N', "", []TR31OptionalBlock{{"PB", "0000"}}}, key},
+		{kbpk, TR31Header{TR31VersionB, "P0", 'T', 'E', "00", 'N', "", []TR31OptionalBlock{{"K", "0000"}}}, key},
+		{kbpk, TR31Header{TR31VersionB, "P0", 'T', 'E', "00", 'N', "", []TR31OptionalBlock{{"KS", "\n"}}}, key},
+		{kbpk, TR31Header{TR31VersionB, "P0", 'T', 'E', "00", 'N', "", nil}, nil},
+		{kbpk, TR31Header{TR31VersionB, "P0", 'T', 'E', "00", 'N', "", nil}, make([]byte, 5000)},
+	} {
+		if _, err := TR31Wrap(tt.kbpk, &tt.header, tt.key); err == nil {
+			t.Errorf("#%d: TR31Wrap() succeeded", i)
+		}
+	}
+}
+
+func Test_TR31Unwrap_Invalid(t *testing.T) {
+	kbpk, block := hexBytes(tr31Tests[1].kbpk), tr31Tests[1].block
+	for i, tt := range []struct {
+		kbpk  []byte
+		block string
+	}{
+		{kbpk[:16], block},
+		{kbpk, "A" + block[1:]},
+		{kbpk, block[:len(block)-1]},
+		{kbpk, block[:1] + "0119" + block[5:len(block)-1]},
+		{kbpk, block[:1] + "+120" + block[5:]},
+		// The MAC covers the header and the key.
+		{kbpk, strings.Replace(block, "K0TB01", "K0TE01", 1)},
+		{kbpk, block[:len(block)-17] + "0" + block[len(block)-16:]},
+		{kbpk, block[:len(block)-1] + "0"},
+		{kbpk, strings.Replace(block, "KS18", "KS19", 1)},
+		{kbpk, strings.Replace(block, "0100KS", "0200KS", 1)},
+		{kbpk, strings.Replace(block, "D8AB", "X8AB", 1)},
+		{kbpk, block[:12] + "00" + block[14:]},
+		{kbpk, "B0016K0TB01E0000"},
+	} {
+		if _, _, err := TR31Unwrap(tt.kbpk, tt.block); err == nil {
+			t.Errorf("#%d: TR31Unwrap() succeeded", i)
+		}
+	}
+}
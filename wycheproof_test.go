@@ -0,0 +1,342 @@
+// Project Wycheproof
+// https://github.com/C2SP/wycheproof
+//
+// testdata/wycheproof holds vector files copied unchanged from the
+// testvectors_v1 directory of the project. It has no vectors for DES or
+// 3DES, nor for CTR mode on its own.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AirWSW/go-crypto/aes"
+)
+
+type wycheproofFile struct {
+	Algorithm     string            `json:"algorithm"`
+	Schema        string            `json:"schema"`
+	NumberOfTests int               `json:"numberOfTests"`
+	TestGroups    []json.RawMessage `json:"testGroups"`
+}
+
+// wycheproofTest holds the fields common to all test cases.
+type wycheproofTest struct {
+	TcID    int      `json:"tcId"`
+	Comment string   `json:"comment"`
+	Flags   []string `json:"flags"`
+	Result  string   `json:"result"`
+}
+
+func (tc *wycheproofTest) valid() bool { return tc.Result == "valid" }
+
+func (tc *wycheproofTest) hasFlag(flag string) bool {
+	for _, f := range tc.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// wycheproofHex is a byte string encoded in hex.
+type wycheproofHex []byte
+
+func (h *wycheproofHex) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	d, err := hex.DecodeString(s)
+	*h = d
+	return err
+}
+
+// runWycheproof decodes the test groups of the named file, which must use
+// the given schema, into new values of the type returned by newGroup and
+// passes each to run. It checks that every test case was counted.
+func runWycheproof(t *testing.T, name, schema string, newGroup func() interface{}, run func(group interface{}) int) {
+	b, err := os.ReadFile(filepath.Join("testdata", "wycheproof", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f wycheproofFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		t.Fatalf("%s: %s", name, err)
+	}
+	if f.Schema != schema {
+		t.Fatalf("%s: schema %s, want %s", name, f.Schema, schema)
+	}
+	n := 0
+	for _, raw := range f.TestGroups {
+		g := newGroup()
+		if err := json.Unmarshal(raw, g); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		n += run(g)
+	}
+	if n != f.NumberOfTests {
+		t.Errorf("%s: ran %d tests, want %d", name, n, f.NumberOfTests)
+	}
+}
+
+type wycheproofCBCGroup struct {
+	Tests []struct {
+		wycheproofTest
+		Key wycheproofHex `json:"key"`
+		IV  wycheproofHex `json:"iv"`
+		Msg wycheproofHex `json:"msg"`
+		CT  wycheproofHex `json:"ct"`
+	} `json:"tests"`
+}
+
+func Test_Wycheproof_CBC(t *testing.T) {
+	runWycheproof(t, "aes_cbc_pkcs5_test.json", "ind_cpa_test_schema_v1.json",
+		func() interface{} { return new(wycheproofCBCGroup) },
+		func(group interface{}) int {
+			g := group.(*wycheproofCBCGroup)
+			for _, tc := range g.Tests {
+				block := aesBlock(tc.Key)
+				pt, err := crypt(block, "cbc", tc.IV, tc.CT, true)
+				if !tc.valid() {
+					// Bad padding, or a ciphertext that is not a whole
+					// number of blocks.
+					if err == nil {
+						t.Errorf("#%d (%s): decrypt() = %x, want error", tc.TcID, tc.Comment, pt)
+					}
+					continue
+				}
+				if err != nil || !bytes.Equal(pt, tc.Msg) {
+					t.Errorf("#%d (%s): decrypt() = %x, %v, want %x", tc.TcID, tc.Comment, pt, err, tc.Msg)
+				}
+				if ct, err := crypt(block, "cbc", tc.IV, tc.Msg, false); err != nil || !bytes.Equal(ct, tc.CT) {
+					t.Errorf("#%d (%s): encrypt() = %x, %v, want %x", tc.TcID, tc.Comment, ct, err, tc.CT)
+				}
+			}
+			return len(g.Tests)
+		})
+}
+
+type wycheproofAEADGroup struct {
+	Tests []struct {
+		wycheproofTest
+		Key wycheproofHex `json:"key"`
+		IV  wycheproofHex `json:"iv"`
+		AAD wycheproofHex `json:"aad"`
+		Msg wycheproofHex `json:"msg"`
+		CT  wycheproofHex `json:"ct"`
+		Tag wycheproofHex `json:"tag"`
+	} `json:"tests"`
+}
+
+func Test_Wycheproof_GCM(t *testing.T) {
+	runWycheproof(t, "aes_gcm_test.json", "aead_test_schema_v1.json",
+		func() interface{} { return new(wycheproofAEADGroup) },
+		func(group interface{}) int {
+			g := group.(*wycheproofAEADGroup)
+			for _, tc := range g.Tests {
+				aead, err := NewGCMWithNonceSize(aesBlock(tc.Key), len(tc.IV))
+				if err != nil {
+					if tc.valid() {
+						t.Errorf("#%d (%s): NewGCMWithNonceSize() = %s", tc.TcID, tc.Comment, err)
+					}
+					continue
+				}
+				sealed := append(append([]byte(nil), tc.CT...), tc.Tag...)
+				pt, err := aead.Open(nil, tc.IV, sealed, tc.AAD)
+				if !tc.valid() {
+					if err == nil {
+						t.Errorf("#%d (%s): Open() = %x, want error", tc.TcID, tc.Comment, pt)
+					}
+					continue
+				}
+				if err != nil || !bytes.Equal(pt, tc.Msg) {
+					t.Errorf("#%d (%s): Open() = %x, %v, want %x", tc.TcID, tc.Comment, pt, err, tc.Msg)
+				}
+				if got := aead.Seal(nil, tc.IV, tc.Msg, tc.AAD); !bytes.Equal(got, sealed) {
+					t.Errorf("#%d (%s): Seal() = %x, want %x", tc.TcID, tc.Comment, got, sealed)
+				}
+			}
+			return len(g.Tests)
+		})
+}
+
+// The ciphertext of AES-GCM with a 96-bit IV is the message in CTR mode
+// from the counter IV || 00000002. The 32-bit increment of GCM and the
+// 128-bit one of NewCTR agree as the counter does not wrap.
+func Test_Wycheproof_CTR(t *testing.T) {
+	runWycheproof(t, "aes_gcm_test.json", "aead_test_schema_v1.json",
+		func() interface{} { return new(wycheproofAEADGroup) },
+		func(group interface{}) int {
+			g := group.(*wycheproofAEADGroup)
+			for _, tc := range g.Tests {
+				if !tc.valid() || len(tc.IV) != 12 {
+					continue
+				}
+				iv := append(append([]byte(nil), tc.IV...), 0, 0, 0, 2)
+				got := make([]byte, len(tc.Msg))
+				NewCTR(aesBlock(tc.Key), iv).XORKeyStream(got, tc.Msg)
+				if !bytes.Equal(got, tc.CT) {
+					t.Errorf("#%d (%s): XORKeyStream() = %x, want %x", tc.TcID, tc.Comment, got, tc.CT)
+				}
+			}
+			return len(g.Tests)
+		})
+}
+
+type wycheproofMACGroup struct {
+	Tests []struct {
+		wycheproofTest
+		Key wycheproofHex `json:"key"`
+		IV  wycheproofHex `json:"iv"`
+		Msg wycheproofHex `json:"msg"`
+		Tag wycheproofHex `json:"tag"`
+	} `json:"tests"`
+}
+
+func Test_Wycheproof_GMAC(t *testing.T) {
+	runWycheproof(t, "aes_gmac_test.json", "mac_with_iv_test_schema_v1.json",
+		func() interface{} { return new(wycheproofMACGroup) },
+		func(group interface{}) int {
+			g := group.(*wycheproofMACGroup)
+			for _, tc := range g.Tests {
+				m, err := NewGMAC(aesBlock(tc.Key), tc.IV)
+				if err != nil {
+					if tc.valid() {
+						t.Errorf("#%d (%s): NewGMAC() = %s", tc.TcID, tc.Comment, err)
+					}
+					continue
+				}
+				m.Write(tc.Msg)
+				if got := m.Sum(nil); bytes.Equal(got, tc.Tag) != tc.valid() {
+					t.Errorf("#%d (%s): Sum() = %x, tag %x is %s", tc.TcID, tc.Comment, got, tc.Tag, tc.Result)
+				}
+			}
+			return len(g.Tests)
+		})
+}
+
+type wycheproofCMACGroup struct {
+	Tests []struct {
+		wycheproofTest
+		Key wycheproofHex `json:"key"`
+		Msg wycheproofHex `json:"msg"`
+		Tag wycheproofHex `json:"tag"`
+	} `json:"tests"`
+}
+
+func Test_Wycheproof_CMAC(t *testing.T) {
+	runWycheproof(t, "aes_cmac_test.json", "mac_test_schema_v1.json",
+		func() interface{} { return new(wycheproofCMACGroup) },
+		func(group interface{}) int {
+			g := group.(*wycheproofCMACGroup)
+			for _, tc := range g.Tests {
+				block, err := aes.NewCipher(tc.Key)
+				if err != nil {
+					if tc.valid() {
+						t.Errorf("#%d (%s): NewCipher() = %s", tc.TcID, tc.Comment, err)
+					}
+					continue
+				}
+				m, err := NewCMAC(block)
+				if err != nil {
+					t.Fatalf("NewCMAC() = %s", err)
+				}
+				m.Write(tc.Msg)
+				if got := m.Sum(nil); bytes.Equal(got, tc.Tag) != tc.valid() {
+					t.Errorf("#%d (%s): Sum() = %x, tag %x is %s", tc.TcID, tc.Comment, got, tc.Tag, tc.Result)
+				}
+			}
+			return len(g.Tests)
+		})
+}
+
+// FF1 tests flagged SmallMessageSize are valid under the first edition of
+// SP 800-38G, which allowed a smaller domain than FF1 does now. They may be
+// rejected.
+type wycheproofFF1Group struct {
+	Radix    int    `json:"radix"`
+	Alphabet string `json:"alphabet"`
+	Tests    []struct {
+		wycheproofTest
+		Key   wycheproofHex   `json:"key"`
+		Tweak wycheproofHex   `json:"tweak"`
+		Msg   json.RawMessage `json:"msg"`
+		CT    json.RawMessage `json:"ct"`
+	} `json:"tests"`
+}
+
+func Test_Wycheproof_FF1(t *testing.T) {
+	for _, name := range []string{"aes_ff1_radix10_test.json", "aes_ff1_base36_test.json"} {
+		schema := "fpe_list_test_schema.json"
+		if strings.Contains(name, "base") {
+			schema = "fpe_str_test_schema.json"
+		}
+		runWycheproof(t, name, schema,
+			func() interface{} { return new(wycheproofFF1Group) },
+			func(group interface{}) int {
+				g := group.(*wycheproofFF1Group)
+				for _, tc := range g.Tests {
+					var f *FF1
+					var err error
+					if g.Alphabet != "" {
+						f, err = NewFF1(tc.Key, g.Alphabet, tc.Tweak)
+					} else {
+						f, err = NewFF1Radix(tc.Key, g.Radix, tc.Tweak)
+					}
+					if err == nil {
+						err = checkFF1(f, g.Alphabet != "", tc.Tweak, tc.Msg, tc.CT)
+					}
+					switch {
+					case tc.valid() && err != nil && !tc.hasFlag("SmallMessageSize"):
+						t.Errorf("%s #%d (%s): %s", name, tc.TcID, tc.Comment, err)
+					case !tc.valid() && err == nil:
+						t.Errorf("%s #%d (%s): accepted", name, tc.TcID, tc.Comment)
+					}
+				}
+				return len(g.Tests)
+			})
+	}
+}
+
+// checkFF1 encrypts msg and decrypts ct, given as JSON strings or lists of
+// numerals, and returns an error if either fails or gives the wrong result.
+func checkFF1(f *FF1, str bool, tweak []byte, msg, ct json.RawMessage) error {
+	if str {
+		var m, c string
+		if err := json.Unmarshal(msg, &m); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(ct, &c); err != nil {
+			return err
+		}
+		if got, err := f.Encrypt(m); err != nil || got != c {
+			return fmt.Errorf("Encrypt() = %q, %v, want %q", got, err, c)
+		}
+		if got, err := f.Decrypt(c); err != nil || got != m {
+			return fmt.Errorf("Decrypt() = %q, %v, want %q", got, err, m)
+		}
+		return nil
+	}
+	var m, c []uint16
+	if err := json.Unmarshal(msg, &m); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(ct, &c); err != nil {
+		return err
+	}
+	if got, err := f.EncryptNumerals(m, tweak); err != nil || fmt.Sprint(got) != fmt.Sprint(c) {
+		return fmt.Errorf("EncryptNumerals() = %v, %v, want %v", got, err, c)
+	}
+	if got, err := f.DecryptNumerals(c, tweak); err != nil || fmt.Sprint(got) != fmt.Sprint(m) {
+		return fmt.Errorf("DecryptNumerals() = %v, %v, want %v", got, err, m)
+	}
+	return nil
+}